@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+// envPrefix prefixes the environment variables overriding the configuration
+const envPrefix = "GHA_AUTOSCALER_"
+
+// envConfigFile is the environment variable with the path of the configuration file
+const envConfigFile = envPrefix + "CONFIG"
+
+// bindConfigFlags defines the flags overriding the configuration, writing to cfg when parsed.
+// Every flag can also be set with an environment variable derived from its name, see envName.
+func bindConfigFlags(flags *flag.FlagSet, cfg *config.Config) {
+	flags.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "Calculate changes without applying them to the cluster")
+	flags.IntVar(&cfg.CPUBufferPercent, "cpu-buffer-percent", cfg.CPUBufferPercent, "Percentage of CPU capacity to reserve as buffer (0-100)")
+	flags.IntVar(&cfg.MemoryBufferPercent, "memory-buffer-percent", cfg.MemoryBufferPercent, "Percentage of memory capacity to reserve as buffer (0-100)")
+	flags.Var((*quantity)(&cfg.CPUReserve), "cpu-reserve", "CPU kept free across the cluster before applying the buffer (e.g., 4, 500m)")
+	flags.Var((*quantity)(&cfg.MemoryReserve), "memory-reserve", "Memory kept free across the cluster before applying the buffer (e.g., 16Gi)")
+	flags.Var((*quantity)(&cfg.NodeCPUReserve), "node-cpu-reserve", "CPU kept free on every node before applying the buffer (e.g., 500m)")
+	flags.Var((*quantity)(&cfg.NodeMemoryReserve), "node-memory-reserve", "Memory kept free on every node before applying the buffer (e.g., 1Gi)")
+	flags.BoolVar(&cfg.ReserveUnscheduledPods, "reserve-unscheduled-pods", cfg.ReserveUnscheduledPods, "Keep the requests of pending pods not bound to a node yet free")
+	flags.BoolVar(&cfg.NodeFilter.ExcludeCordoned, "exclude-cordoned-nodes", cfg.NodeFilter.ExcludeCordoned, "Don't count cordoned nodes toward capacity")
+	flags.BoolVar(&cfg.NodeFilter.ExcludeToBeDeleted, "exclude-to-be-deleted-nodes", cfg.NodeFilter.ExcludeToBeDeleted, "Don't count nodes the cluster-autoscaler is removing toward capacity")
+	flags.BoolVar(&cfg.NodeFilter.ExcludeTainted, "exclude-tainted-nodes", cfg.NodeFilter.ExcludeTainted, "Don't count nodes with NoSchedule or NoExecute taints toward capacity, even if runner sets tolerate them")
+	flags.StringVar(&cfg.NodeFilter.LabelSelector, "node-selector", cfg.NodeFilter.LabelSelector, "Only count nodes matching this label selector toward capacity (e.g., node-pool in (ci,ci-xl))")
+	flags.StringVar(&cfg.NodeFilter.ExcludeLabelSelector, "exclude-node-selector", cfg.NodeFilter.ExcludeLabelSelector, "Don't count nodes matching this label selector toward capacity (e.g., node-pool=system)")
+	flags.Var((*stringList)(&cfg.Namespaces), "namespaces", "Comma-separated namespaces to watch for runner sets (empty: all namespaces)")
+	flags.DurationVar(&cfg.ReconcileInterval, "reconcile-interval", cfg.ReconcileInterval, "Resync interval when no events trigger a reconciliation (e.g., 30s, 5m)")
+	flags.DurationVar(&cfg.ReconcileDebounce, "reconcile-debounce", cfg.ReconcileDebounce, "How long to collect events before reconciling (e.g., 2s)")
+	flags.StringVar(&cfg.CapacityMode, "capacity-mode", cfg.CapacityMode, "Capacity model: \"cluster\" (pooled) or \"node\" (per-node bin-packing)")
+	flags.StringVar(&cfg.AllocationStrategy, "allocation-strategy", cfg.AllocationStrategy, "Allocation strategy: \"strict-priority\", \"fair-share\", \"max-min-fairness\" or \"weighted-drf\"")
+	flags.BoolVar(&cfg.DemandAware, "demand-aware", cfg.DemandAware, "Limit idle and lightly used runner sets to their current demand")
+	flags.BoolVar(&cfg.LeaderElection.Enabled, "leader-elect", cfg.LeaderElection.Enabled, "Enable Lease-based leader election, required when running more than one replica")
+	flags.StringVar(&cfg.LeaderElection.LeaseNamespace, "leader-election-namespace", cfg.LeaderElection.LeaseNamespace, "Namespace of the leader election Lease (default: namespace the controller runs in)")
+	flags.StringVar(&cfg.LeaderElection.LeaseName, "leader-election-name", cfg.LeaderElection.LeaseName, "Name of the leader election Lease")
+	flags.DurationVar(&cfg.LeaderElection.LeaseDuration, "leader-election-lease-duration", cfg.LeaderElection.LeaseDuration, "How long standby replicas wait before taking over the Lease")
+	flags.DurationVar(&cfg.LeaderElection.RenewDeadline, "leader-election-renew-deadline", cfg.LeaderElection.RenewDeadline, "How long the leader retries renewing the Lease before giving up leadership")
+	flags.DurationVar(&cfg.LeaderElection.RetryPeriod, "leader-election-retry-period", cfg.LeaderElection.RetryPeriod, "How often replicas try to acquire or renew the Lease")
+	flags.StringVar(&cfg.MetricsBindAddress, "metrics-bind-address", cfg.MetricsBindAddress, "Address serving Prometheus metrics (\"0\" to disable)")
+	flags.StringVar(&cfg.HealthProbeBindAddress, "health-probe-bind-address", cfg.HealthProbeBindAddress, "Address serving the /healthz and /readyz probes (\"0\" to disable)")
+	flags.IntVar(&cfg.LivenessReconcileIntervals, "liveness-reconcile-intervals", cfg.LivenessReconcileIntervals, "Resync intervals without a completed reconciliation before the liveness probe fails")
+	flags.BoolVar(&cfg.Webhook.Enabled, "webhook", cfg.Webhook.Enabled, "Serve a validating admission webhook rejecting runner sets with invalid autoscaling annotations")
+	flags.IntVar(&cfg.Webhook.Port, "webhook-port", cfg.Webhook.Port, "Port of the webhook server")
+	flags.StringVar(&cfg.Webhook.CertDir, "webhook-cert-dir", cfg.Webhook.CertDir, "Directory containing the webhook serving certificate tls.crt and key tls.key")
+	flags.BoolVar(&cfg.WatchConfig, "watch-config", cfg.WatchConfig, "Reload the configuration file when it changes, keeping the last valid configuration")
+}
+
+// loadConfig builds the controller configuration. Later sources override earlier ones:
+// the defaults, the configuration file, GHA_AUTOSCALER_* environment variables and the flags set on the command line.
+func loadConfig(configFile string, getenv func(key string) string, flags *flag.FlagSet) (*config.Config, error) {
+	cfg := config.DefaultConfig()
+	if configFile != "" {
+		if err := config.LoadFile(configFile, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// Environment variables and flags are applied through a second set of the same flags bound to cfg,
+	// so both are parsed the same way
+	overrides := flag.NewFlagSet(flags.Name(), flag.ContinueOnError)
+	bindConfigFlags(overrides, cfg)
+
+	var err error
+	overrides.VisitAll(func(f *flag.Flag) {
+		value := getenv(envName(f.Name))
+		if value == "" || err != nil {
+			return
+		}
+		if setErr := overrides.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, envName(f.Name), setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if overrides.Lookup(f.Name) == nil || err != nil {
+			return
+		}
+		if setErr := overrides.Set(f.Name, f.Value.String()); setErr != nil {
+			err = fmt.Errorf("invalid value %q for flag -%s: %w", f.Value.String(), f.Name, setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// envName returns the environment variable overriding a flag, e.g. GHA_AUTOSCALER_RECONCILE_INTERVAL for --reconcile-interval
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// stringList is a comma-separated list flag
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*l = list
+	return nil
+}
+
+// quantity is a Kubernetes resource quantity flag, e.g. 500m or 16Gi
+type quantity resource.Quantity
+
+func (q *quantity) String() string {
+	if q == nil {
+		return "0"
+	}
+	return (*resource.Quantity)(q).String()
+}
+
+func (q *quantity) Set(value string) error {
+	parsed, err := resource.ParseQuantity(value)
+	if err != nil {
+		return err
+	}
+	*q = quantity(parsed)
+	return nil
+}
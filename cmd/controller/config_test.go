@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestLoadConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+cpuBufferPercent: 20
+memoryBufferPercent: 20
+namespaces: [from-file]
+reconcileInterval: 1m
+dryRun: true
+`
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		check   func(t *testing.T, cfg *config.Config)
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.CPUBufferPercent != 10 || cfg.ReconcileInterval != 30*time.Second {
+					t.Errorf("config = %+v, want defaults", cfg)
+				}
+			},
+		},
+		{
+			name: "file overrides defaults",
+			file: configFile,
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.CPUBufferPercent != 20 || cfg.ReconcileInterval != time.Minute || !cfg.DryRun {
+					t.Errorf("config = %+v, want values from file", cfg)
+				}
+				if cfg.ReconcileDebounce != 5*time.Second {
+					t.Errorf("ReconcileDebounce = %v, want default 5s", cfg.ReconcileDebounce)
+				}
+			},
+		},
+		{
+			name: "environment overrides file",
+			file: configFile,
+			env: map[string]string{
+				"GHA_AUTOSCALER_CPU_BUFFER_PERCENT": "30",
+				"GHA_AUTOSCALER_NAMESPACES":         "github-arc, ci",
+				"GHA_AUTOSCALER_LEADER_ELECT":       "true",
+			},
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.CPUBufferPercent != 30 {
+					t.Errorf("CPUBufferPercent = %v, want 30", cfg.CPUBufferPercent)
+				}
+				if cfg.MemoryBufferPercent != 20 {
+					t.Errorf("MemoryBufferPercent = %v, want 20 from file", cfg.MemoryBufferPercent)
+				}
+				if strings.Join(cfg.Namespaces, ",") != "github-arc,ci" {
+					t.Errorf("Namespaces = %v, want [github-arc ci]", cfg.Namespaces)
+				}
+				if !cfg.LeaderElection.Enabled {
+					t.Error("LeaderElection.Enabled = false, want true")
+				}
+			},
+		},
+		{
+			name: "flags override environment and file",
+			file: configFile,
+			env: map[string]string{
+				"GHA_AUTOSCALER_CPU_BUFFER_PERCENT": "30",
+				"GHA_AUTOSCALER_RECONCILE_INTERVAL": "2m",
+			},
+			args: []string{"--cpu-buffer-percent", "40", "--dry-run=false", "--namespaces", "", "--node-memory-reserve", "1Gi", "--exclude-cordoned-nodes=false", "--node-selector", "node-pool in (ci,ci-xl)", "--reserve-unscheduled-pods"},
+			check: func(t *testing.T, cfg *config.Config) {
+				if cfg.CPUBufferPercent != 40 {
+					t.Errorf("CPUBufferPercent = %v, want 40", cfg.CPUBufferPercent)
+				}
+				if cfg.ReconcileInterval != 2*time.Minute {
+					t.Errorf("ReconcileInterval = %v, want 2m from environment", cfg.ReconcileInterval)
+				}
+				if cfg.DryRun {
+					t.Error("DryRun = true, want false from flag")
+				}
+				if len(cfg.Namespaces) != 0 {
+					t.Errorf("Namespaces = %v, want all namespaces", cfg.Namespaces)
+				}
+				if cfg.NodeMemoryReserve.String() != "1Gi" {
+					t.Errorf("NodeMemoryReserve = %v, want 1Gi", cfg.NodeMemoryReserve.String())
+				}
+				if !cfg.ReserveUnscheduledPods {
+					t.Error("ReserveUnscheduledPods = false, want true from flag")
+				}
+				if cfg.NodeFilter.ExcludeCordoned || cfg.NodeFilter.LabelSelector != "node-pool in (ci,ci-xl)" {
+					t.Errorf("NodeFilter = %+v, want cordoned nodes and selector from flags", cfg.NodeFilter)
+				}
+			},
+		},
+		{
+			name:    "invalid environment variable",
+			env:     map[string]string{"GHA_AUTOSCALER_RECONCILE_INTERVAL": "often"},
+			wantErr: `invalid value "often" for GHA_AUTOSCALER_RECONCILE_INTERVAL`,
+		},
+		{
+			name:    "invalid reserve",
+			env:     map[string]string{"GHA_AUTOSCALER_CPU_RESERVE": "4 cores"},
+			wantErr: `invalid value "4 cores" for GHA_AUTOSCALER_CPU_RESERVE`,
+		},
+		{
+			name:    "invalid node selector",
+			args:    []string{"--node-selector", "node-pool in ci"},
+			wantErr: "nodeFilter.labelSelector is invalid",
+		},
+		{
+			name:    "invalid result",
+			args:    []string{"--capacity-mode", "pool"},
+			wantErr: `capacityMode must be "cluster" or "node", got "pool"`,
+		},
+		{
+			name:    "missing file",
+			file:    filepath.Join(t.TempDir(), "missing.yaml"),
+			wantErr: "failed to read config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("controller", flag.ContinueOnError)
+			bindConfigFlags(flags, config.DefaultConfig())
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+			getenv := func(key string) string {
+				return tt.env[key]
+			}
+
+			cfg, err := loadConfig(tt.file, getenv, flags)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadConfig() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfig() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got := envName("leader-election-lease-duration"); got != "GHA_AUTOSCALER_LEADER_ELECTION_LEASE_DURATION" {
+		t.Errorf("envName() = %q, want GHA_AUTOSCALER_LEADER_ELECTION_LEASE_DURATION", got)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+// configPollInterval is how often the configuration file is checked for changes.
+// The file is polled instead of watched, as mounted ConfigMaps are updated by swapping symlinks.
+const configPollInterval = 10 * time.Second
+
+// configWatcher reloads the configuration when the configuration file changes
+type configWatcher struct {
+	logger *slog.Logger
+	path   string
+
+	// load builds the configuration the same way as at startup, so environment variables and flags
+	// keep overriding the file
+	load func() (*config.Config, error)
+
+	// apply is called with each valid changed configuration
+	apply func(cfg *config.Config)
+
+	// current is the configuration in use, content the file content it was last loaded from
+	current *config.Config
+	content []byte
+}
+
+// run polls the configuration file until the context is canceled
+func (w *configWatcher) run(ctx context.Context, interval time.Duration) {
+	w.logger.Info("watching configuration file for changes", "path", w.path, "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// reload loads the configuration if the file changed and applies it. Invalid configurations are
+// rejected, keeping the last valid configuration.
+func (w *configWatcher) reload() {
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		w.logger.Warn("failed to read configuration file, keeping the current configuration", "path", w.path, "error", err)
+		return
+	}
+	if bytes.Equal(content, w.content) {
+		return
+	}
+	// Each content is only loaded once, so an invalid configuration is reported once instead of on every poll
+	w.content = content
+
+	cfg, err := w.load()
+	if err != nil {
+		w.logger.Error("invalid configuration, keeping the last valid configuration", "path", w.path, "error", err)
+		return
+	}
+
+	changes := config.Diff(w.current, cfg)
+	if len(changes) == 0 {
+		w.logger.Debug("configuration file changed without changing the configuration", "path", w.path)
+		return
+	}
+	for _, change := range changes {
+		if change.RequiresRestart() {
+			w.logger.Warn("configuration change requires a restart, keeping the current value",
+				"setting", change.Setting,
+				"old", change.Old,
+				"new", change.New)
+			continue
+		}
+		w.logger.Info("configuration changed",
+			"setting", change.Setting,
+			"old", change.Old,
+			"new", change.New)
+	}
+
+	cfg.KeepRestartSettings(w.current)
+	if len(config.Diff(w.current, cfg)) == 0 {
+		return
+	}
+	w.current = cfg
+	w.apply(cfg)
+	w.logger.Info("configuration reloaded", "path", w.path)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestConfigWatcher_Reload(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+	}
+
+	// The flag overrides the file, also when reloading
+	flags := flag.NewFlagSet("controller", flag.ContinueOnError)
+	bindConfigFlags(flags, config.DefaultConfig())
+	if err := flags.Parse([]string{"--reconcile-debounce", "2s"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	getenv := func(string) string { return "" }
+
+	writeConfig("cpuBufferPercent: 10\n")
+	initial, err := loadConfig(configFile, getenv, flags)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+
+	var applied []*config.Config
+	watcher := &configWatcher{
+		logger: slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError})),
+		path:   configFile,
+		load: func() (*config.Config, error) {
+			return loadConfig(configFile, getenv, flags)
+		},
+		apply: func(cfg *config.Config) {
+			applied = append(applied, cfg)
+		},
+		current: initial,
+	}
+
+	// Unchanged configuration
+	watcher.reload()
+	if len(applied) != 0 {
+		t.Fatalf("applied %d configurations for an unchanged file, want 0", len(applied))
+	}
+
+	// Changed buffer
+	writeConfig("cpuBufferPercent: 20\nnamespaces: [github-arc]\n")
+	watcher.reload()
+	if len(applied) != 1 {
+		t.Fatalf("applied %d configurations, want 1", len(applied))
+	}
+	if applied[0].CPUBufferPercent != 20 || len(applied[0].Namespaces) != 1 {
+		t.Errorf("applied configuration = %+v, want 20%% CPU buffer in github-arc", applied[0])
+	}
+	if applied[0].ReconcileDebounce != 2*time.Second {
+		t.Errorf("ReconcileDebounce = %v, want 2s from flag", applied[0].ReconcileDebounce)
+	}
+
+	// Invalid configuration keeps the last valid one
+	writeConfig("cpuBufferPercent: 200\n")
+	watcher.reload()
+	if len(applied) != 1 {
+		t.Fatalf("applied %d configurations after invalid change, want 1", len(applied))
+	}
+	if watcher.current.CPUBufferPercent != 20 {
+		t.Errorf("current CPUBufferPercent = %v, want 20", watcher.current.CPUBufferPercent)
+	}
+
+	// Settings requiring a restart are not applied
+	writeConfig("cpuBufferPercent: 20\nnamespaces: [github-arc]\ndryRun: true\n")
+	watcher.reload()
+	if len(applied) != 1 {
+		t.Fatalf("applied %d configurations after restart-only change, want 1", len(applied))
+	}
+
+	// ... but are kept at their running value when other settings change
+	writeConfig("cpuBufferPercent: 30\nnamespaces: [github-arc]\ndryRun: true\n")
+	watcher.reload()
+	if len(applied) != 2 {
+		t.Fatalf("applied %d configurations, want 2", len(applied))
+	}
+	if applied[1].CPUBufferPercent != 30 || applied[1].DryRun {
+		t.Errorf("applied configuration = %+v, want 30%% CPU buffer without dry-run", applied[1])
+	}
+}
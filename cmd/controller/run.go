@@ -10,11 +10,16 @@ import (
 	"syscall"
 
 	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
 	"github.com/kula-app/gha-runner-autoscaler-controller/internal/controller"
@@ -27,14 +32,28 @@ import (
 // If the run function returns an error, it means the application failed to complete.
 //
 // The logic of the run function must stay isolated so it can be tested in parallel.
-func run(ctx context.Context, args []string, _ func(key string) string, _ *os.File) error {
-	// Parse command-line flags
+func run(ctx context.Context, args []string, getenv func(key string) string, stdout *os.File) error {
+	// Parse command-line flags. Flags overriding the configuration are bound to a scratch copy here,
+	// as only the flags set on the command line are applied on top of the configuration file and environment.
 	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
-	dryRun := flags.Bool("dry-run", false, "Calculate changes without applying them to the cluster")
-	reconcileInterval := flags.Duration("reconcile-interval", 0, "Override reconcile interval (e.g., 30s, 5m)")
+	configFile := flags.String("config", "", "Path of a YAML or JSON configuration file (env: "+envConfigFile+")")
+	validate := flags.Bool("validate", false, "List runner sets with invalid autoscaling annotations and exit (non-zero if any are invalid)")
+	bindConfigFlags(flags, config.DefaultConfig())
 	if err := flags.Parse(args[1:]); err != nil {
 		return fmt.Errorf("failed to parse flags: %w", err)
 	}
+	if *configFile == "" {
+		*configFile = getenv(envConfigFile)
+	}
+
+	// Load controller configuration
+	controllerConfig, err := loadConfig(*configFile, getenv, flags)
+	if err != nil {
+		return err
+	}
+	if controllerConfig.WatchConfig && *configFile == "" {
+		return fmt.Errorf("watching the configuration requires a configuration file (--config or %s)", envConfigFile)
+	}
 
 	// Derive a context that is canceled on OS interrupt/termination. This allows
 	// us to coordinate a graceful shutdown across goroutines when the process is
@@ -48,7 +67,7 @@ func run(ctx context.Context, args []string, _ func(key string) string, _ *os.Fi
 	logger := slog.New(logging.NewTerminalHandler())
 
 	logger.Info("GitHub Actions Runner Autoscaler Controller starting")
-	if *dryRun {
+	if controllerConfig.DryRun {
 		logger.Warn("DRY-RUN MODE ENABLED: Changes will be calculated but not applied to the cluster")
 	}
 
@@ -77,34 +96,111 @@ func run(ctx context.Context, args []string, _ func(key string) string, _ *os.Fi
 		return fmt.Errorf("failed to register AutoscalingRunnerSet scheme: %w", err)
 	}
 
-	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
-	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %w", err)
-	}
-
-	// Load controller configuration
-	controllerConfig := config.DefaultConfig()
-	controllerConfig.DryRun = *dryRun
-
-	// Override reconcile interval if provided
-	if *reconcileInterval > 0 {
-		controllerConfig.ReconcileInterval = *reconcileInterval
-	}
-
 	logger.Info("controller configuration loaded",
+		"config_file", *configFile,
 		"cpu_buffer_percent", controllerConfig.CPUBufferPercent,
 		"memory_buffer_percent", controllerConfig.MemoryBufferPercent,
+		"capacity_mode", controllerConfig.CapacityMode,
+		"allocation_strategy", controllerConfig.AllocationStrategy,
+		"demand_aware", controllerConfig.DemandAware,
 		"reconcile_interval", controllerConfig.ReconcileInterval,
+		"reconcile_debounce", controllerConfig.ReconcileDebounce,
 		"namespaces", controllerConfig.Namespaces,
-		"dry_run", controllerConfig.DryRun)
+		"dry_run", controllerConfig.DryRun,
+		"leader_election", controllerConfig.LeaderElection.Enabled,
+		"metrics_bind_address", controllerConfig.MetricsBindAddress,
+		"health_probe_bind_address", controllerConfig.HealthProbeBindAddress,
+		"webhook", controllerConfig.Webhook.Enabled,
+		"watch_config", controllerConfig.WatchConfig)
+
+	// In validation mode, check the runner sets once and exit instead of running the controller
+	if *validate {
+		return validateRunnerSets(ctx, cfg, scheme, logger, controllerConfig, stdout)
+	}
+
+	// Dry-run replicas don't change anything, so they observe without taking part in the election.
+	// Otherwise a dry-run replica could hold the Lease and keep the actual leader from reconciling.
+	leaderElection := controllerConfig.LeaderElection.Enabled && !controllerConfig.DryRun
+	if controllerConfig.LeaderElection.Enabled && controllerConfig.DryRun {
+		logger.Info("leader election skipped in dry-run mode, observing without leadership")
+	}
 
-	// Create the reconciler
-	reconciler := controller.NewReconciler(k8sClient, logger, controllerConfig)
+	// Create a manager maintaining informer caches of the watched resources.
+	// Runner sets are only cached in the configured namespaces, nodes and pods cluster-wide for capacity.
+	ctrl.SetLogger(logr.FromSlogHandler(logger.Handler()))
+	cacheOptions := cache.Options{
+		// Managed fields are never read, drop them to keep the pod cache small
+		DefaultTransform: cache.TransformStripManagedFields(),
+	}
+	// When watching the configuration, runner sets are cached in all namespaces so the namespaces can be changed
+	if len(controllerConfig.Namespaces) > 0 && !controllerConfig.WatchConfig {
+		namespaces := make(map[string]cache.Config, len(controllerConfig.Namespaces))
+		for _, namespace := range controllerConfig.Namespaces {
+			namespaces[namespace] = cache.Config{}
+		}
+		cacheOptions.ByObject = map[client.Object]cache.ByObject{
+			&actionsv1alpha1.AutoscalingRunnerSet{}: {Namespaces: namespaces},
+			&actionsv1alpha1.EphemeralRunnerSet{}:   {Namespaces: namespaces},
+		}
+	}
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme: scheme,
+		Cache:  cacheOptions,
+		Metrics: metricsserver.Options{
+			BindAddress: controllerConfig.MetricsBindAddress,
+		},
+		HealthProbeBindAddress: controllerConfig.HealthProbeBindAddress,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    controllerConfig.Webhook.Port,
+			CertDir: controllerConfig.Webhook.CertDir,
+		}),
+		LeaderElection:          leaderElection,
+		LeaderElectionID:        controllerConfig.LeaderElection.LeaseName,
+		LeaderElectionNamespace: controllerConfig.LeaderElection.LeaseNamespace,
+		LeaseDuration:           &controllerConfig.LeaderElection.LeaseDuration,
+		RenewDeadline:           &controllerConfig.LeaderElection.RenewDeadline,
+		RetryPeriod:             &controllerConfig.LeaderElection.RetryPeriod,
+		// Give up the Lease on shutdown, so a standby replica takes over right away
+		LeaderElectionReleaseOnCancel: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	// Create the reconciler, reading from the manager's caches
+	reconciler := controller.NewReconciler(mgr.GetClient(), logger, controllerConfig)
+	if err := reconciler.SetupWithManager(ctx, mgr); err != nil {
+		return fmt.Errorf("failed to set up reconciler: %w", err)
+	}
+
+	// Serve the validating webhook on all replicas, as the API server may call any of them
+	if controllerConfig.Webhook.Enabled {
+		if err := controller.SetupWebhookWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to set up webhook: %w", err)
+		}
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			return fmt.Errorf("failed to add webhook readiness check: %w", err)
+		}
+	}
+
+	// Reload the configuration when the file changes, on all replicas so standby replicas take over with it
+	if controllerConfig.WatchConfig {
+		watcher := &configWatcher{
+			logger: logger,
+			path:   *configFile,
+			load: func() (*config.Config, error) {
+				return loadConfig(*configFile, getenv, flags)
+			},
+			apply:   reconciler.UpdateConfig,
+			current: controllerConfig,
+		}
+		go watcher.run(ctx, configPollInterval)
+	}
 
-	// Run the reconciliation loop
-	logger.Info("starting reconciliation loop")
-	if err := reconciler.Run(ctx); err != nil && err != context.Canceled {
-		return fmt.Errorf("reconciliation loop failed: %w", err)
+	// Run the reconciliation loop once the caches are synced and, with leader election, the Lease is acquired
+	logger.Info("starting manager")
+	if err := mgr.Start(ctx); err != nil {
+		return fmt.Errorf("manager failed: %w", err)
 	}
 
 	logger.Info("controller stopped gracefully")
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/controller"
+)
+
+// validateRunnerSets lists the runner sets opted in to autoscaling whose annotations are invalid.
+// It reads directly from the API server and returns an error if any runner set is misconfigured,
+// so it can gate CI pipelines applying runner set manifests.
+func validateRunnerSets(ctx context.Context, cfg *rest.Config, scheme *runtime.Scheme, logger *slog.Logger, controllerConfig *config.Config, stdout *os.File) error {
+	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	reconciler := controller.NewReconciler(k8sClient, logger, controllerConfig)
+	valid, invalid, err := reconciler.Validate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to validate runner sets: %w", err)
+	}
+
+	for _, runnerSet := range invalid {
+		fmt.Fprintf(stdout, "%s/%s: %v\n", runnerSet.Namespace, runnerSet.Name, runnerSet.Err)
+	}
+
+	logger.Info("runner sets validated", "valid", valid, "invalid", len(invalid))
+	if len(invalid) > 0 {
+		return fmt.Errorf("%d runner sets opted in to autoscaling have invalid annotations", len(invalid))
+	}
+	return nil
+}
@@ -2,10 +2,15 @@ package config
 
 import (
 	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 // Annotation keys used on AutoscalingRunnerSet resources
 const (
+	// AnnotationPrefix is the common prefix of all annotation keys
+	AnnotationPrefix = "kula.app/gha-runner-autoscaler-"
+
 	// AnnotationEnabled enables autoscaling for this runner set (opt-in)
 	AnnotationEnabled = "kula.app/gha-runner-autoscaler-enabled"
 
@@ -20,6 +25,53 @@ const (
 
 	// AnnotationMinRunners sets minimum guaranteed maxRunners (doesn't keep pods running)
 	AnnotationMinRunners = "kula.app/gha-runner-autoscaler-min-runners"
+
+	// AnnotationEphemeralStorage specifies ephemeral storage requirements (supports "20Gi", "500Mi", or raw bytes)
+	AnnotationEphemeralStorage = "kula.app/gha-runner-autoscaler-ephemeral-storage"
+
+	// AnnotationExtendedResources specifies extended resources and hugepages requirements as comma-separated
+	// name=quantity pairs (e.g. "nvidia.com/gpu=1,hugepages-2Mi=512Mi")
+	AnnotationExtendedResources = "kula.app/gha-runner-autoscaler-extended-resources"
+
+	// AnnotationPodMultiplier multiplies the runner footprint derived from the pod template (supports decimals, e.g. "1.5").
+	// Use it for pods created next to the runner pod, e.g. "2" for kubernetes container mode where each job runs in its own pod.
+	AnnotationPodMultiplier = "kula.app/gha-runner-autoscaler-pod-multiplier"
+
+	// AnnotationPodsPerRunner specifies how many pods a runner takes on its node while running a job
+	// (default: the pod multiplier rounded up)
+	AnnotationPodsPerRunner = "kula.app/gha-runner-autoscaler-pods-per-runner"
+
+	// AnnotationMaxRunners records the operator-declared maxRunners ceiling (0 means no cap).
+	// The controller writes it on first adoption because it overwrites spec.maxRunners afterwards.
+	AnnotationMaxRunners = "kula.app/gha-runner-autoscaler-max-runners"
+
+	// AnnotationStatus carries the allocation status written by the controller as JSON
+	// (last reconcile time, computed and applied maxRunners, limiting factor, fair share, errors)
+	AnnotationStatus = "kula.app/gha-runner-autoscaler-status"
+)
+
+// Capacity modes supported by the controller
+const (
+	// CapacityModeCluster sums free capacity across all nodes into a single pool
+	CapacityModeCluster = "cluster"
+
+	// CapacityModeNode tracks free capacity per node and bin-packs runners onto nodes
+	CapacityModeNode = "node"
+)
+
+// Allocation strategies supported by the controller
+const (
+	// AllocationStrategyStrictPriority allocates capacity in priority order; lower priorities get what's left
+	AllocationStrategyStrictPriority = "strict-priority"
+
+	// AllocationStrategyFairShare splits capacity proportionally to priority weights
+	AllocationStrategyFairShare = "fair-share"
+
+	// AllocationStrategyMaxMinFairness equalizes the dominant resource share of all runner sets
+	AllocationStrategyMaxMinFairness = "max-min-fairness"
+
+	// AllocationStrategyWeightedDRF equalizes the dominant resource share of all runner sets, weighted by priority
+	AllocationStrategyWeightedDRF = "weighted-drf"
 )
 
 // Config represents the controller configuration
@@ -30,14 +82,117 @@ type Config struct {
 	// MemoryBufferPercent is the percentage of memory capacity to reserve as buffer (0-100)
 	MemoryBufferPercent int `json:"memoryBufferPercent" validate:"required,min=0,max=100"`
 
-	// ReconcileInterval is how often to run the reconciliation loop
+	// CPUReserve and MemoryReserve are kept free across the cluster, e.g. "4" cores and "16Gi".
+	// Reserves are subtracted from free capacity before the percentage buffers are applied.
+	CPUReserve    resource.Quantity `json:"cpuReserve"`
+	MemoryReserve resource.Quantity `json:"memoryReserve"`
+
+	// NodeCPUReserve and NodeMemoryReserve are kept free on every node, on top of the cluster reserves
+	NodeCPUReserve    resource.Quantity `json:"nodeCPUReserve"`
+	NodeMemoryReserve resource.Quantity `json:"nodeMemoryReserve"`
+
+	// ReserveUnscheduledPods keeps the requests of pending pods not bound to a node yet free, so runners
+	// don't take the capacity they are waiting for
+	ReserveUnscheduledPods bool `json:"reserveUnscheduledPods"`
+
+	// NodeFilter selects the nodes counted toward capacity
+	NodeFilter NodeFilterConfig `json:"nodeFilter"`
+
+	// CapacityMode selects how free capacity is modeled ("cluster" or "node")
+	CapacityMode string `json:"capacityMode" validate:"required,oneof=cluster node"`
+
+	// AllocationStrategy selects how capacity is distributed among runner sets
+	AllocationStrategy string `json:"allocationStrategy" validate:"required,oneof=strict-priority fair-share max-min-fairness weighted-drf"`
+
+	// DemandAware limits idle and lightly used runner sets to their current demand, so their capacity
+	// goes to runner sets that need it
+	DemandAware bool `json:"demandAware"`
+
+	// ReconcileInterval is how often to resync when no watch event triggered a reconciliation
 	ReconcileInterval time.Duration `json:"reconcileInterval" validate:"required"`
 
+	// ReconcileDebounce is how long to collect watch events before reconciling, so bursts of events
+	// (e.g. many runner pods finishing) cause a single reconciliation
+	ReconcileDebounce time.Duration `json:"reconcileDebounce" validate:"required"`
+
 	// Namespaces to watch for AutoscalingRunnerSets (empty slice means all namespaces)
 	Namespaces []string `json:"namespaces"`
 
 	// DryRun when enabled will calculate changes but not apply them to the cluster
 	DryRun bool `json:"dryRun"`
+
+	// LeaderElection configures Lease-based leader election between controller replicas
+	LeaderElection LeaderElectionConfig `json:"leaderElection"`
+
+	// MetricsBindAddress is the address serving Prometheus metrics on /metrics ("0" disables the endpoint)
+	MetricsBindAddress string `json:"metricsBindAddress" validate:"required"`
+
+	// HealthProbeBindAddress is the address serving the /healthz and /readyz probes ("0" disables the endpoints)
+	HealthProbeBindAddress string `json:"healthProbeBindAddress" validate:"required"`
+
+	// LivenessReconcileIntervals is the number of ReconcileIntervals without a completed reconciliation
+	// after which the liveness probe fails
+	LivenessReconcileIntervals int `json:"livenessReconcileIntervals" validate:"required,min=1"`
+
+	// Webhook configures the validating admission webhook for AutoscalingRunnerSets
+	Webhook WebhookConfig `json:"webhook"`
+
+	// WatchConfig reloads the configuration file when it changes, e.g. when a mounted ConfigMap is updated
+	WatchConfig bool `json:"watchConfig"`
+}
+
+// NodeFilterConfig selects the nodes counted toward capacity. Nodes that aren't ready are never counted.
+type NodeFilterConfig struct {
+	// ExcludeCordoned drops nodes marked unschedulable, e.g. by kubectl cordon or drain
+	ExcludeCordoned bool `json:"excludeCordoned"`
+
+	// ExcludeToBeDeleted drops nodes the cluster-autoscaler is removing (ToBeDeletedByClusterAutoscaler taint)
+	ExcludeToBeDeleted bool `json:"excludeToBeDeleted"`
+
+	// ExcludeTainted drops nodes with any NoSchedule or NoExecute taint, even if runner sets tolerate it
+	ExcludeTainted bool `json:"excludeTainted"`
+
+	// LabelSelector only counts nodes matching the label selector, e.g. "node-pool in (ci,ci-xl)" (empty means all nodes)
+	LabelSelector string `json:"labelSelector"`
+
+	// ExcludeLabelSelector drops nodes matching the label selector, e.g. "node-pool=system" (empty means none)
+	ExcludeLabelSelector string `json:"excludeLabelSelector"`
+}
+
+// WebhookConfig configures the validating admission webhook, rejecting AutoscalingRunnerSets
+// with invalid autoscaling annotations
+type WebhookConfig struct {
+	// Enabled turns on the webhook server
+	Enabled bool `json:"enabled"`
+
+	// Port is the port the webhook server listens on
+	Port int `json:"port" validate:"required,min=1,max=65535"`
+
+	// CertDir is the directory containing the serving certificate tls.crt and key tls.key
+	// (empty means the controller-runtime default in the temp directory)
+	CertDir string `json:"certDir"`
+}
+
+// LeaderElectionConfig configures Lease-based leader election.
+// Only the leader reconciles; standby replicas take over when the leader stops renewing the Lease.
+type LeaderElectionConfig struct {
+	// Enabled turns on leader election (required when running more than one replica)
+	Enabled bool `json:"enabled"`
+
+	// LeaseNamespace is the namespace of the Lease (empty means the namespace the controller runs in)
+	LeaseNamespace string `json:"leaseNamespace"`
+
+	// LeaseName is the name of the Lease
+	LeaseName string `json:"leaseName" validate:"required"`
+
+	// LeaseDuration is how long standby replicas wait before taking over a Lease that isn't renewed
+	LeaseDuration time.Duration `json:"leaseDuration" validate:"required"`
+
+	// RenewDeadline is how long the leader retries renewing the Lease before giving up leadership
+	RenewDeadline time.Duration `json:"renewDeadline" validate:"required"`
+
+	// RetryPeriod is how often replicas try to acquire or renew the Lease
+	RetryPeriod time.Duration `json:"retryPeriod" validate:"required"`
 }
 
 // DefaultConfig returns a default configuration
@@ -45,8 +200,37 @@ func DefaultConfig() *Config {
 	return &Config{
 		CPUBufferPercent:    10,
 		MemoryBufferPercent: 10,
+		CPUReserve:          resource.MustParse("0"),
+		MemoryReserve:       resource.MustParse("0"),
+		NodeCPUReserve:      resource.MustParse("0"),
+		NodeMemoryReserve:   resource.MustParse("0"),
+		CapacityMode:        CapacityModeCluster,
+		AllocationStrategy:  AllocationStrategyFairShare,
+		DemandAware:         false,
 		ReconcileInterval:   30 * time.Second,
+		ReconcileDebounce:   5 * time.Second,
 		Namespaces:          []string{}, // Empty means all namespaces
 		DryRun:              false,
+		NodeFilter: NodeFilterConfig{
+			ExcludeCordoned:    true,
+			ExcludeToBeDeleted: true,
+			ExcludeTainted:     false,
+		},
+		ReserveUnscheduledPods: false,
+		LeaderElection: LeaderElectionConfig{
+			Enabled:       false,
+			LeaseName:     "gha-runner-autoscaler-controller",
+			LeaseDuration: 15 * time.Second,
+			RenewDeadline: 10 * time.Second,
+			RetryPeriod:   2 * time.Second,
+		},
+		MetricsBindAddress:         ":8080",
+		HealthProbeBindAddress:     ":8081",
+		LivenessReconcileIntervals: 3,
+		Webhook: WebhookConfig{
+			Enabled: false,
+			Port:    9443,
+		},
+		WatchConfig: false,
 	}
 }
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -22,12 +28,32 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("MemoryBufferPercent = %v, want 10", cfg.MemoryBufferPercent)
 	}
 
+	// Check capacity mode
+	if cfg.CapacityMode != CapacityModeCluster {
+		t.Errorf("CapacityMode = %v, want %v", cfg.CapacityMode, CapacityModeCluster)
+	}
+
+	// Check allocation strategy
+	if cfg.AllocationStrategy != AllocationStrategyFairShare {
+		t.Errorf("AllocationStrategy = %v, want %v", cfg.AllocationStrategy, AllocationStrategyFairShare)
+	}
+
+	// Check demand-aware allocation
+	if cfg.DemandAware != false {
+		t.Errorf("DemandAware = %v, want false", cfg.DemandAware)
+	}
+
 	// Check reconcile interval
 	expectedInterval := 30 * time.Second
 	if cfg.ReconcileInterval != expectedInterval {
 		t.Errorf("ReconcileInterval = %v, want %v", cfg.ReconcileInterval, expectedInterval)
 	}
 
+	// Check reconcile debounce
+	if cfg.ReconcileDebounce != 5*time.Second {
+		t.Errorf("ReconcileDebounce = %v, want %v", cfg.ReconcileDebounce, 5*time.Second)
+	}
+
 	// Check namespaces
 	if cfg.Namespaces == nil {
 		t.Error("Namespaces is nil, want empty slice")
@@ -40,6 +66,54 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.DryRun != false {
 		t.Errorf("DryRun = %v, want false", cfg.DryRun)
 	}
+
+	// Check unscheduled pods reservation
+	if cfg.ReserveUnscheduledPods {
+		t.Error("ReserveUnscheduledPods = true, want false")
+	}
+
+	// Check node filter
+	if !cfg.NodeFilter.ExcludeCordoned || !cfg.NodeFilter.ExcludeToBeDeleted || cfg.NodeFilter.ExcludeTainted {
+		t.Errorf("NodeFilter = %+v, want cordoned and to be deleted nodes excluded", cfg.NodeFilter)
+	}
+	if cfg.NodeFilter.LabelSelector != "" || cfg.NodeFilter.ExcludeLabelSelector != "" {
+		t.Errorf("NodeFilter = %+v, want no label selectors", cfg.NodeFilter)
+	}
+
+	// Check leader election
+	if cfg.LeaderElection.Enabled != false {
+		t.Errorf("LeaderElection.Enabled = %v, want false", cfg.LeaderElection.Enabled)
+	}
+	if cfg.LeaderElection.LeaseName != "gha-runner-autoscaler-controller" {
+		t.Errorf("LeaderElection.LeaseName = %v, want gha-runner-autoscaler-controller", cfg.LeaderElection.LeaseName)
+	}
+	if cfg.LeaderElection.LeaseDuration <= cfg.LeaderElection.RenewDeadline {
+		t.Errorf("LeaderElection.LeaseDuration = %v, want more than RenewDeadline %v", cfg.LeaderElection.LeaseDuration, cfg.LeaderElection.RenewDeadline)
+	}
+	if cfg.LeaderElection.RenewDeadline <= cfg.LeaderElection.RetryPeriod {
+		t.Errorf("LeaderElection.RenewDeadline = %v, want more than RetryPeriod %v", cfg.LeaderElection.RenewDeadline, cfg.LeaderElection.RetryPeriod)
+	}
+
+	// Check metrics bind address
+	if cfg.MetricsBindAddress != ":8080" {
+		t.Errorf("MetricsBindAddress = %v, want :8080", cfg.MetricsBindAddress)
+	}
+
+	// Check health probes
+	if cfg.HealthProbeBindAddress != ":8081" {
+		t.Errorf("HealthProbeBindAddress = %v, want :8081", cfg.HealthProbeBindAddress)
+	}
+	if cfg.LivenessReconcileIntervals != 3 {
+		t.Errorf("LivenessReconcileIntervals = %v, want 3", cfg.LivenessReconcileIntervals)
+	}
+
+	// Check webhook
+	if cfg.Webhook.Enabled != false {
+		t.Errorf("Webhook.Enabled = %v, want false", cfg.Webhook.Enabled)
+	}
+	if cfg.Webhook.Port != 9443 {
+		t.Errorf("Webhook.Port = %v, want 9443", cfg.Webhook.Port)
+	}
 }
 
 func TestConfigAnnotations(t *testing.T) {
@@ -69,6 +143,36 @@ func TestConfigAnnotations(t *testing.T) {
 			value: AnnotationPriority,
 			want:  "kula.app/gha-runner-autoscaler-priority",
 		},
+		{
+			name:  "AnnotationEphemeralStorage",
+			value: AnnotationEphemeralStorage,
+			want:  "kula.app/gha-runner-autoscaler-ephemeral-storage",
+		},
+		{
+			name:  "AnnotationExtendedResources",
+			value: AnnotationExtendedResources,
+			want:  "kula.app/gha-runner-autoscaler-extended-resources",
+		},
+		{
+			name:  "AnnotationPodMultiplier",
+			value: AnnotationPodMultiplier,
+			want:  "kula.app/gha-runner-autoscaler-pod-multiplier",
+		},
+		{
+			name:  "AnnotationPodsPerRunner",
+			value: AnnotationPodsPerRunner,
+			want:  "kula.app/gha-runner-autoscaler-pods-per-runner",
+		},
+		{
+			name:  "AnnotationMaxRunners",
+			value: AnnotationMaxRunners,
+			want:  "kula.app/gha-runner-autoscaler-max-runners",
+		},
+		{
+			name:  "AnnotationStatus",
+			value: AnnotationStatus,
+			want:  "kula.app/gha-runner-autoscaler-status",
+		},
 	}
 
 	for _, tt := range tests {
@@ -79,3 +183,355 @@ func TestConfigAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		check   func(t *testing.T, cfg *Config)
+		wantErr string
+	}{
+		{
+			name: "yaml overrides only the given settings",
+			file: "config.yaml",
+			content: `
+cpuBufferPercent: 20
+namespaces: [github-arc, ci]
+reconcileInterval: 1m
+leaderElection:
+  enabled: true
+  leaseDuration: 30s
+webhook:
+  enabled: true
+`,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.CPUBufferPercent != 20 {
+					t.Errorf("CPUBufferPercent = %v, want 20", cfg.CPUBufferPercent)
+				}
+				if cfg.MemoryBufferPercent != 10 {
+					t.Errorf("MemoryBufferPercent = %v, want default 10", cfg.MemoryBufferPercent)
+				}
+				if len(cfg.Namespaces) != 2 || cfg.Namespaces[0] != "github-arc" || cfg.Namespaces[1] != "ci" {
+					t.Errorf("Namespaces = %v, want [github-arc ci]", cfg.Namespaces)
+				}
+				if cfg.ReconcileInterval != time.Minute {
+					t.Errorf("ReconcileInterval = %v, want 1m", cfg.ReconcileInterval)
+				}
+				if cfg.ReconcileDebounce != 5*time.Second {
+					t.Errorf("ReconcileDebounce = %v, want default 5s", cfg.ReconcileDebounce)
+				}
+				if !cfg.LeaderElection.Enabled || cfg.LeaderElection.LeaseDuration != 30*time.Second {
+					t.Errorf("LeaderElection = %+v, want enabled with 30s lease duration", cfg.LeaderElection)
+				}
+				if cfg.LeaderElection.LeaseName != "gha-runner-autoscaler-controller" || cfg.LeaderElection.RenewDeadline != 10*time.Second {
+					t.Errorf("LeaderElection = %+v, want default lease name and renew deadline", cfg.LeaderElection)
+				}
+				if !cfg.Webhook.Enabled || cfg.Webhook.Port != 9443 {
+					t.Errorf("Webhook = %+v, want enabled on default port 9443", cfg.Webhook)
+				}
+			},
+		},
+		{
+			name:    "json",
+			file:    "config.json",
+			content: `{"allocationStrategy": "weighted-drf", "reconcileDebounce": "2s"}`,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.AllocationStrategy != AllocationStrategyWeightedDRF {
+					t.Errorf("AllocationStrategy = %v, want %v", cfg.AllocationStrategy, AllocationStrategyWeightedDRF)
+				}
+				if cfg.ReconcileDebounce != 2*time.Second {
+					t.Errorf("ReconcileDebounce = %v, want 2s", cfg.ReconcileDebounce)
+				}
+			},
+		},
+		{
+			name: "reserves",
+			file: "config.yaml",
+			content: `
+cpuReserve: 4
+memoryReserve: 16Gi
+nodeCPUReserve: 500m
+`,
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.CPUReserve.MilliValue() != 4000 {
+					t.Errorf("CPUReserve = %v, want 4", cfg.CPUReserve.String())
+				}
+				if cfg.MemoryReserve.Value() != 16*1024*1024*1024 {
+					t.Errorf("MemoryReserve = %v, want 16Gi", cfg.MemoryReserve.String())
+				}
+				if cfg.NodeCPUReserve.MilliValue() != 500 {
+					t.Errorf("NodeCPUReserve = %v, want 500m", cfg.NodeCPUReserve.String())
+				}
+				if !cfg.NodeMemoryReserve.IsZero() {
+					t.Errorf("NodeMemoryReserve = %v, want default 0", cfg.NodeMemoryReserve.String())
+				}
+			},
+		},
+		{
+			name:    "invalid reserve",
+			file:    "config.yaml",
+			content: "memoryReserve: 16 GB\n",
+			wantErr: "failed to parse config file",
+		},
+		{
+			name:    "unknown field",
+			file:    "config.yaml",
+			content: "cpuBuferPercent: 20\n",
+			wantErr: `unknown field "cpuBuferPercent"`,
+		},
+		{
+			name:    "unknown nested field",
+			file:    "config.yaml",
+			content: "leaderElection:\n  leaseDurationSeconds: 30\n",
+			wantErr: `unknown field "leaseDurationSeconds"`,
+		},
+		{
+			name:    "duration without unit",
+			file:    "config.yaml",
+			content: "reconcileInterval: 30\n",
+			wantErr: `must be a string like "30s"`,
+		},
+		{
+			name:    "invalid duration",
+			file:    "config.yaml",
+			content: "leaderElection:\n  retryPeriod: soon\n",
+			wantErr: `invalid duration "soon"`,
+		},
+		{
+			name:    "invalid yaml",
+			file:    "config.yaml",
+			content: "namespaces: [github-arc\n",
+			wantErr: "failed to parse config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg := DefaultConfig()
+			err := LoadFile(path, cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadFile() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadFile_Missing(t *testing.T) {
+	err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"), DefaultConfig())
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadFile() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr []string
+	}{
+		{
+			name:   "defaults",
+			modify: func(cfg *Config) {},
+		},
+		{
+			name: "zero buffers",
+			modify: func(cfg *Config) {
+				cfg.CPUBufferPercent = 0
+				cfg.MemoryBufferPercent = 0
+			},
+		},
+		{
+			name: "buffers out of range",
+			modify: func(cfg *Config) {
+				cfg.CPUBufferPercent = 101
+				cfg.MemoryBufferPercent = -1
+			},
+			wantErr: []string{
+				"cpuBufferPercent must be between 0 and 100, got 101",
+				"memoryBufferPercent must be between 0 and 100, got -1",
+			},
+		},
+		{
+			name: "negative reserves",
+			modify: func(cfg *Config) {
+				cfg.CPUReserve = resource.MustParse("-1")
+				cfg.NodeMemoryReserve = resource.MustParse("-1Gi")
+			},
+			wantErr: []string{
+				"cpuReserve must not be negative, got -1",
+				"nodeMemoryReserve must not be negative, got -1Gi",
+			},
+		},
+		{
+			name: "unknown capacity mode and allocation strategy",
+			modify: func(cfg *Config) {
+				cfg.CapacityMode = "pool"
+				cfg.AllocationStrategy = "round-robin"
+			},
+			wantErr: []string{
+				`capacityMode must be "cluster" or "node", got "pool"`,
+				`got "round-robin"`,
+			},
+		},
+		{
+			name: "zero intervals",
+			modify: func(cfg *Config) {
+				cfg.ReconcileInterval = 0
+				cfg.ReconcileDebounce = 0
+				cfg.LivenessReconcileIntervals = 0
+			},
+			wantErr: []string{
+				"reconcileInterval must be positive",
+				"reconcileDebounce must be positive",
+				"livenessReconcileIntervals must be at least 1",
+			},
+		},
+		{
+			name: "empty namespace",
+			modify: func(cfg *Config) {
+				cfg.Namespaces = []string{"github-arc", ""}
+			},
+			wantErr: []string{"namespaces must not contain empty names"},
+		},
+		{
+			name: "lease duration not above renew deadline",
+			modify: func(cfg *Config) {
+				cfg.LeaderElection.LeaseDuration = 10 * time.Second
+			},
+			wantErr: []string{"leaderElection.leaseDuration (10s) must be greater than leaderElection.renewDeadline (10s)"},
+		},
+		{
+			name: "node label selectors",
+			modify: func(cfg *Config) {
+				cfg.NodeFilter.LabelSelector = "node-pool in (ci, ci-xl)"
+				cfg.NodeFilter.ExcludeLabelSelector = "!spot"
+			},
+		},
+		{
+			name: "invalid node label selectors",
+			modify: func(cfg *Config) {
+				cfg.NodeFilter.LabelSelector = "node-pool in ci"
+				cfg.NodeFilter.ExcludeLabelSelector = "=system"
+			},
+			wantErr: []string{
+				"nodeFilter.labelSelector is invalid",
+				"nodeFilter.excludeLabelSelector is invalid",
+			},
+		},
+		{
+			name: "empty addresses and invalid webhook port",
+			modify: func(cfg *Config) {
+				cfg.MetricsBindAddress = ""
+				cfg.HealthProbeBindAddress = ""
+				cfg.Webhook.Port = 70000
+			},
+			wantErr: []string{
+				"metricsBindAddress must not be empty",
+				"healthProbeBindAddress must not be empty",
+				"webhook.port must be between 1 and 65535, got 70000",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldConfig := DefaultConfig()
+	newConfig := DefaultConfig()
+	newConfig.CPUBufferPercent = 20
+	newConfig.CPUReserve = resource.MustParse("0m")
+	newConfig.MemoryReserve = resource.MustParse("1Gi")
+	newConfig.ReserveUnscheduledPods = true
+	newConfig.Namespaces = nil
+	newConfig.LeaderElection.LeaseDuration = 30 * time.Second
+	newConfig.ReconcileInterval = time.Minute
+
+	changes := Diff(oldConfig, newConfig)
+
+	want := []struct {
+		setting         string
+		requiresRestart bool
+	}{
+		{setting: "cpuBufferPercent", requiresRestart: false},
+		{setting: "memoryReserve", requiresRestart: false},
+		{setting: "reserveUnscheduledPods", requiresRestart: false},
+		{setting: "reconcileInterval", requiresRestart: false},
+		{setting: "leaderElection.leaseDuration", requiresRestart: true},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("Diff() = %+v, want %d changes", changes, len(want))
+	}
+	for i, change := range changes {
+		if change.Setting != want[i].setting {
+			t.Errorf("Diff()[%d].Setting = %q, want %q", i, change.Setting, want[i].setting)
+		}
+		if change.RequiresRestart() != want[i].requiresRestart {
+			t.Errorf("%s RequiresRestart() = %v, want %v", change.Setting, change.RequiresRestart(), want[i].requiresRestart)
+		}
+	}
+	if changes[0].Old != 10 || changes[0].New != 20 {
+		t.Errorf("cpuBufferPercent change = %v -> %v, want 10 -> 20", changes[0].Old, changes[0].New)
+	}
+	if changes[1].Old != "0" || changes[1].New != "1Gi" {
+		t.Errorf("memoryReserve change = %v -> %v, want 0 -> 1Gi", changes[1].Old, changes[1].New)
+	}
+}
+
+func TestConfig_KeepRestartSettings(t *testing.T) {
+	running := DefaultConfig()
+	reloaded := DefaultConfig()
+	reloaded.CPUBufferPercent = 20
+	reloaded.DryRun = true
+	reloaded.DemandAware = true
+	reloaded.LeaderElection.Enabled = true
+	reloaded.MetricsBindAddress = ":9090"
+	reloaded.HealthProbeBindAddress = ":9091"
+	reloaded.Webhook.Port = 8443
+	reloaded.WatchConfig = true
+
+	reloaded.KeepRestartSettings(running)
+
+	for _, change := range Diff(running, reloaded) {
+		if change.RequiresRestart() {
+			t.Errorf("KeepRestartSettings() kept change of %s", change.Setting)
+		}
+	}
+	if reloaded.CPUBufferPercent != 20 {
+		t.Errorf("CPUBufferPercent = %v, want 20", reloaded.CPUBufferPercent)
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// restartSettings are the settings only read at startup. Changing them requires restarting the controller.
+var restartSettings = []string{
+	"dryRun",
+	"demandAware",
+	"leaderElection",
+	"metricsBindAddress",
+	"healthProbeBindAddress",
+	"webhook",
+	"watchConfig",
+}
+
+// Change is a setting that differs between two configurations
+type Change struct {
+	// Setting is the config file key of the setting, e.g. "leaderElection.leaseDuration"
+	Setting string
+
+	Old any
+	New any
+}
+
+// RequiresRestart reports whether the changed setting only takes effect after a restart
+func (c Change) RequiresRestart() bool {
+	for _, setting := range restartSettings {
+		if c.Setting == setting || strings.HasPrefix(c.Setting, setting+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the settings that differ between two configurations, in the order of the Config fields
+func Diff(oldConfig, newConfig *Config) []Change {
+	return diffStruct("", reflect.ValueOf(*oldConfig), reflect.ValueOf(*newConfig))
+}
+
+// KeepRestartSettings copies the settings requiring a restart from the running configuration,
+// so a reloaded configuration only changes settings that can take effect right away
+func (c *Config) KeepRestartSettings(running *Config) {
+	c.DryRun = running.DryRun
+	c.DemandAware = running.DemandAware
+	c.LeaderElection = running.LeaderElection
+	c.MetricsBindAddress = running.MetricsBindAddress
+	c.HealthProbeBindAddress = running.HealthProbeBindAddress
+	c.Webhook = running.Webhook
+	c.WatchConfig = running.WatchConfig
+}
+
+// diffStruct compares the fields of two structs by their json names, descending into nested structs.
+// Quantities are compared by value, so "1" and "1000m" are equal.
+func diffStruct(prefix string, oldValue, newValue reflect.Value) []Change {
+	var changes []Change
+	for i := range oldValue.NumField() {
+		field := oldValue.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		oldField, newField := oldValue.Field(i), newValue.Field(i)
+		if oldQuantity, ok := oldField.Interface().(resource.Quantity); ok {
+			newQuantity := newField.Interface().(resource.Quantity)
+			if oldQuantity.Cmp(newQuantity) != 0 {
+				changes = append(changes, Change{
+					Setting: prefix + name,
+					Old:     oldQuantity.String(),
+					New:     newQuantity.String(),
+				})
+			}
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			changes = append(changes, diffStruct(prefix+name+".", oldField, newField)...)
+			continue
+		}
+		if !equal(oldField, newField) {
+			changes = append(changes, Change{
+				Setting: prefix + name,
+				Old:     oldField.Interface(),
+				New:     newField.Interface(),
+			})
+		}
+	}
+	return changes
+}
+
+// equal compares two setting values, treating nil and empty lists as equal
+func equal(oldValue, newValue reflect.Value) bool {
+	if oldValue.Kind() == reflect.Slice && oldValue.Len() == 0 && newValue.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(oldValue.Interface(), newValue.Interface())
+}
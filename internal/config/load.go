@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LoadFile reads a YAML or JSON configuration file into cfg. Fields missing in the file keep their
+// current values, so the file only needs to contain the settings that differ from the defaults.
+// Unknown fields are rejected, so typos don't go unnoticed.
+func LoadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// YAML is converted to JSON, so the json tags and the JSON decoding below apply to both formats
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if err := decodeStrict(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes the configuration, reading durations as strings like "30s"
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	raw := struct {
+		*plain
+		ReconcileInterval duration `json:"reconcileInterval"`
+		ReconcileDebounce duration `json:"reconcileDebounce"`
+	}{
+		plain:             (*plain)(c),
+		ReconcileInterval: duration(c.ReconcileInterval),
+		ReconcileDebounce: duration(c.ReconcileDebounce),
+	}
+	if err := decodeStrict(data, &raw); err != nil {
+		return err
+	}
+
+	c.ReconcileInterval = time.Duration(raw.ReconcileInterval)
+	c.ReconcileDebounce = time.Duration(raw.ReconcileDebounce)
+	return nil
+}
+
+// UnmarshalJSON decodes the leader election configuration, reading durations as strings like "15s"
+func (c *LeaderElectionConfig) UnmarshalJSON(data []byte) error {
+	type plain LeaderElectionConfig
+	raw := struct {
+		*plain
+		LeaseDuration duration `json:"leaseDuration"`
+		RenewDeadline duration `json:"renewDeadline"`
+		RetryPeriod   duration `json:"retryPeriod"`
+	}{
+		plain:         (*plain)(c),
+		LeaseDuration: duration(c.LeaseDuration),
+		RenewDeadline: duration(c.RenewDeadline),
+		RetryPeriod:   duration(c.RetryPeriod),
+	}
+	if err := decodeStrict(data, &raw); err != nil {
+		return err
+	}
+
+	c.LeaseDuration = time.Duration(raw.LeaseDuration)
+	c.RenewDeadline = time.Duration(raw.RenewDeadline)
+	c.RetryPeriod = time.Duration(raw.RetryPeriod)
+	return nil
+}
+
+// duration is a time.Duration decoded from a string like "30s" instead of nanoseconds
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid duration %s: must be a string like \"30s\"", data)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// decodeStrict decodes JSON into v, rejecting unknown fields
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
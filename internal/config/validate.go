@@ -0,0 +1,75 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Validate checks the configuration, returning all invalid settings at once.
+// Settings are named by their config file keys.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.CPUBufferPercent >= 0 && c.CPUBufferPercent <= 100,
+		"cpuBufferPercent must be between 0 and 100, got %d", c.CPUBufferPercent)
+	check(c.MemoryBufferPercent >= 0 && c.MemoryBufferPercent <= 100,
+		"memoryBufferPercent must be between 0 and 100, got %d", c.MemoryBufferPercent)
+	check(c.CPUReserve.Sign() >= 0, "cpuReserve must not be negative, got %s", c.CPUReserve.String())
+	check(c.MemoryReserve.Sign() >= 0, "memoryReserve must not be negative, got %s", c.MemoryReserve.String())
+	check(c.NodeCPUReserve.Sign() >= 0, "nodeCPUReserve must not be negative, got %s", c.NodeCPUReserve.String())
+	check(c.NodeMemoryReserve.Sign() >= 0, "nodeMemoryReserve must not be negative, got %s", c.NodeMemoryReserve.String())
+	if _, err := labels.Parse(c.NodeFilter.LabelSelector); err != nil {
+		check(false, "nodeFilter.labelSelector is invalid: %v", err)
+	}
+	if _, err := labels.Parse(c.NodeFilter.ExcludeLabelSelector); err != nil {
+		check(false, "nodeFilter.excludeLabelSelector is invalid: %v", err)
+	}
+
+	switch c.CapacityMode {
+	case CapacityModeCluster, CapacityModeNode:
+	default:
+		check(false, "capacityMode must be %q or %q, got %q", CapacityModeCluster, CapacityModeNode, c.CapacityMode)
+	}
+
+	switch c.AllocationStrategy {
+	case AllocationStrategyStrictPriority, AllocationStrategyFairShare,
+		AllocationStrategyMaxMinFairness, AllocationStrategyWeightedDRF:
+	default:
+		check(false, "allocationStrategy must be %q, %q, %q or %q, got %q",
+			AllocationStrategyStrictPriority, AllocationStrategyFairShare,
+			AllocationStrategyMaxMinFairness, AllocationStrategyWeightedDRF, c.AllocationStrategy)
+	}
+
+	check(c.ReconcileInterval > 0, "reconcileInterval must be positive, got %v", c.ReconcileInterval)
+	check(c.ReconcileDebounce > 0, "reconcileDebounce must be positive, got %v", c.ReconcileDebounce)
+	for _, namespace := range c.Namespaces {
+		check(namespace != "", "namespaces must not contain empty names")
+	}
+
+	// The leader must give up before standby replicas take over, and retry at least once before giving up
+	check(c.LeaderElection.LeaseName != "", "leaderElection.leaseName must not be empty")
+	check(c.LeaderElection.RetryPeriod > 0, "leaderElection.retryPeriod must be positive, got %v", c.LeaderElection.RetryPeriod)
+	check(c.LeaderElection.RenewDeadline > c.LeaderElection.RetryPeriod,
+		"leaderElection.renewDeadline (%v) must be greater than leaderElection.retryPeriod (%v)",
+		c.LeaderElection.RenewDeadline, c.LeaderElection.RetryPeriod)
+	check(c.LeaderElection.LeaseDuration > c.LeaderElection.RenewDeadline,
+		"leaderElection.leaseDuration (%v) must be greater than leaderElection.renewDeadline (%v)",
+		c.LeaderElection.LeaseDuration, c.LeaderElection.RenewDeadline)
+
+	check(c.MetricsBindAddress != "", "metricsBindAddress must not be empty, use \"0\" to disable metrics")
+	check(c.HealthProbeBindAddress != "", "healthProbeBindAddress must not be empty, use \"0\" to disable probes")
+	check(c.LivenessReconcileIntervals >= 1, "livenessReconcileIntervals must be at least 1, got %d", c.LivenessReconcileIntervals)
+	check(c.Webhook.Port >= 1 && c.Webhook.Port <= 65535, "webhook.port must be between 1 and 65535, got %d", c.Webhook.Port)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
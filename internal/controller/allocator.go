@@ -3,12 +3,23 @@ package controller
 import (
 	"log/slog"
 	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // RunnerSetAllocation represents the calculated maxRunners for a runner set
 type RunnerSetAllocation struct {
+	Namespace  string
 	Name       string
 	MaxRunners int
+
+	// FairShare is the share of its node pool's capacity (0-1) the runner set is entitled to by its weight
+	FairShare float64
+}
+
+// Key returns the namespaced name identifying the allocated runner set
+func (a RunnerSetAllocation) Key() types.NamespacedName {
+	return types.NamespacedName{Namespace: a.Namespace, Name: a.Name}
 }
 
 // Allocator calculates maxRunners for each runner set based on available capacity
@@ -25,43 +36,40 @@ func NewAllocator(logger *slog.Logger) *Allocator {
 
 // Allocate calculates maxRunners for all runner sets based on available capacity
 // It respects priority (higher number = higher priority) and ensures we don't exceed available resources
-func (a *Allocator) Allocate(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64) ([]RunnerSetAllocation, error) {
+func (a *Allocator) Allocate(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error) {
 	allocations := make([]RunnerSetAllocation, 0, len(runnerSets))
 
 	// Sort runner sets by priority (higher priority first)
 	sortedRunnerSets := make([]*RunnerSetResources, len(runnerSets))
 	copy(sortedRunnerSets, runnerSets)
 	sort.Slice(sortedRunnerSets, func(i, j int) bool {
-		// Higher priority first
-		if sortedRunnerSets[i].Priority != sortedRunnerSets[j].Priority {
-			return sortedRunnerSets[i].Priority > sortedRunnerSets[j].Priority
-		}
-		// If priority is equal, sort by name for deterministic behavior
-		return sortedRunnerSets[i].Name < sortedRunnerSets[j].Name
+		return higherPriority(sortedRunnerSets[i], sortedRunnerSets[j])
 	})
 
 	// Track remaining capacity as we allocate
 	remainingCPU := availableCPUMillis
 	remainingMemory := availableMemoryBytes
+	remainingScalar := availableScalar.clone()
 
 	a.logger.Debug("starting allocation",
 		"available_cpu_millis", availableCPUMillis,
 		"available_memory_bytes", availableMemoryBytes,
+		"available_scalar", availableScalar,
 		"runner_sets", len(runnerSets))
 
 	// Process runner sets in priority order
 	for _, rs := range sortedRunnerSets {
 		// Calculate how many runners we can fit
-		maxRunners := a.calculateMaxRunners(rs, remainingCPU, remainingMemory)
+		maxRunners := a.calculateMaxRunners(rs, remainingCPU, remainingMemory, remainingScalar)
 
 		// Enforce minimum runners guarantee
 		if rs.MinRunners > 0 && maxRunners < rs.MinRunners {
 			maxRunners = rs.MinRunners
 		}
 
-		// Apply hard cap from configured maxRunners
-		if rs.ConfiguredMax > 0 && maxRunners > rs.ConfiguredMax {
-			maxRunners = rs.ConfiguredMax
+		// Apply hard cap from configured maxRunners and demand
+		if limit := rs.maxRunnersCap(); limit >= 0 && maxRunners > limit {
+			maxRunners = limit
 		}
 
 		// Allocate the resources
@@ -70,8 +78,10 @@ func (a *Allocator) Allocate(runnerSets []*RunnerSetResources, availableCPUMilli
 
 		remainingCPU -= allocatedCPU
 		remainingMemory -= allocatedMemory
+		remainingScalar.add(rs.ScalarResources, -int64(maxRunners))
 
 		a.logger.Debug("allocated runner set",
+			"namespace", rs.Namespace,
 			"name", rs.Name,
 			"priority", rs.Priority,
 			"max_runners", maxRunners,
@@ -83,6 +93,7 @@ func (a *Allocator) Allocate(runnerSets []*RunnerSetResources, availableCPUMilli
 			"remaining_memory_bytes", remainingMemory)
 
 		allocations = append(allocations, RunnerSetAllocation{
+			Namespace:  rs.Namespace,
 			Name:       rs.Name,
 			MaxRunners: maxRunners,
 		})
@@ -94,7 +105,7 @@ func (a *Allocator) Allocate(runnerSets []*RunnerSetResources, availableCPUMilli
 // AllocateFairShare calculates maxRunners using fair share with priority weights
 // Each runner set gets a proportional share of capacity based on its priority weight
 // This prevents high-priority runner sets from starving low-priority ones
-func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64) ([]RunnerSetAllocation, error) {
+func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error) {
 	if len(runnerSets) == 0 {
 		return []RunnerSetAllocation{}, nil
 	}
@@ -128,6 +139,7 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 	allocations := make([]allocation, 0, len(runnerSets))
 	totalAllocatedCPU := int64(0)
 	totalAllocatedMemory := int64(0)
+	totalAllocatedScalar := ScalarResources{}
 
 	for _, rs := range runnerSets {
 		priority := rs.Priority
@@ -138,14 +150,18 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 		// Calculate this runner set's proportional share of capacity
 		cpuShare := (availableCPUMillis * int64(priority)) / int64(totalPriorityWeight)
 		memoryShare := (availableMemoryBytes * int64(priority)) / int64(totalPriorityWeight)
+		scalarShare := make(ScalarResources, len(availableScalar))
+		for name, amount := range availableScalar {
+			scalarShare[name] = (amount * int64(priority)) / int64(totalPriorityWeight)
+		}
 
 		// Calculate how many runners fit in this share
-		maxRunners := a.calculateMaxRunners(rs, cpuShare, memoryShare)
+		maxRunners := a.calculateMaxRunners(rs, cpuShare, memoryShare, scalarShare)
 
 		// Check if we're capped by configured max
 		cappedByMax := false
-		if rs.ConfiguredMax > 0 && maxRunners > rs.ConfiguredMax {
-			maxRunners = rs.ConfiguredMax
+		if limit := rs.maxRunnersCap(); limit >= 0 && maxRunners > limit {
+			maxRunners = limit
 			cappedByMax = true
 		}
 
@@ -155,8 +171,10 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 
 		totalAllocatedCPU += allocatedCPU
 		totalAllocatedMemory += allocatedMemory
+		totalAllocatedScalar.add(rs.ScalarResources, int64(maxRunners))
 
 		a.logger.Debug("fair share allocation (first pass)",
+			"namespace", rs.Namespace,
 			"name", rs.Name,
 			"priority", rs.Priority,
 			"priority_weight", priority,
@@ -194,8 +212,10 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 
 			totalAllocatedCPU += additionalCPU
 			totalAllocatedMemory += additionalMemory
+			totalAllocatedScalar.add(rs.ScalarResources, int64(additional))
 
 			a.logger.Debug("enforcing minimum runners",
+				"namespace", rs.Namespace,
 				"name", rs.Name,
 				"min_runners", rs.MinRunners,
 				"additional_allocated", additional,
@@ -207,6 +227,8 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 	// Sort by priority for redistribution (higher priority first)
 	remainingCPU := availableCPUMillis - totalAllocatedCPU
 	remainingMemory := availableMemoryBytes - totalAllocatedMemory
+	remainingScalar := availableScalar.clone()
+	remainingScalar.add(totalAllocatedScalar, -1)
 
 	if remainingCPU > 0 || remainingMemory > 0 {
 		a.logger.Debug("redistributing unused capacity",
@@ -217,10 +239,7 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 		sortedAllocations := make([]allocation, len(allocations))
 		copy(sortedAllocations, allocations)
 		sort.Slice(sortedAllocations, func(i, j int) bool {
-			if sortedAllocations[i].runnerSet.Priority != sortedAllocations[j].runnerSet.Priority {
-				return sortedAllocations[i].runnerSet.Priority > sortedAllocations[j].runnerSet.Priority
-			}
-			return sortedAllocations[i].runnerSet.Name < sortedAllocations[j].runnerSet.Name
+			return higherPriority(sortedAllocations[i].runnerSet, sortedAllocations[j].runnerSet)
 		})
 
 		// Try to allocate remaining capacity to runner sets that aren't capped
@@ -228,21 +247,22 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 			alloc := &sortedAllocations[i]
 			rs := alloc.runnerSet
 
-			// Skip if already at configured max
-			if rs.ConfiguredMax > 0 && alloc.maxRunners >= rs.ConfiguredMax {
+			// Skip if already at its cap (configured max or demand limit)
+			limit := rs.maxRunnersCap()
+			if limit >= 0 && alloc.maxRunners >= limit {
 				continue
 			}
 
 			// Calculate how many additional runners we can fit
-			additionalRunners := a.calculateMaxRunners(rs, remainingCPU, remainingMemory)
+			additionalRunners := a.calculateMaxRunners(rs, remainingCPU, remainingMemory, remainingScalar)
 			if additionalRunners == 0 {
 				continue
 			}
 
-			// Apply configured max cap
+			// Apply the cap
 			maxAdditional := additionalRunners
-			if rs.ConfiguredMax > 0 {
-				maxPossible := rs.ConfiguredMax - alloc.maxRunners
+			if limit >= 0 {
+				maxPossible := limit - alloc.maxRunners
 				maxAdditional = min(additionalRunners, maxPossible)
 			}
 
@@ -256,8 +276,10 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 
 				remainingCPU -= additionalCPU
 				remainingMemory -= additionalMemory
+				remainingScalar.add(rs.ScalarResources, -int64(maxAdditional))
 
 				a.logger.Debug("redistributed capacity",
+					"namespace", rs.Namespace,
 					"name", rs.Name,
 					"additional_runners", maxAdditional,
 					"new_max_runners", alloc.maxRunners,
@@ -266,7 +288,7 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 
 				// Update the original allocation
 				for j := range allocations {
-					if allocations[j].runnerSet.Name == rs.Name {
+					if allocations[j].runnerSet.Key() == rs.Key() {
 						allocations[j] = *alloc
 						break
 					}
@@ -279,6 +301,7 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 	results := make([]RunnerSetAllocation, 0, len(allocations))
 	for _, alloc := range allocations {
 		results = append(results, RunnerSetAllocation{
+			Namespace:  alloc.runnerSet.Namespace,
 			Name:       alloc.runnerSet.Name,
 			MaxRunners: alloc.maxRunners,
 		})
@@ -287,8 +310,156 @@ func (a *Allocator) AllocateFairShare(runnerSets []*RunnerSetResources, availabl
 	return results, nil
 }
 
-// calculateMaxRunners calculates how many runners of a given spec can fit in the available capacity
-func (a *Allocator) calculateMaxRunners(rs *RunnerSetResources, availableCPUMillis, availableMemoryBytes int64) int {
+// AllocateMaxMinFairness calculates maxRunners using max-min fairness over the dominant resource (DRF)
+// A runner set's dominant share is the largest of its CPU, memory and extended resource shares of the available capacity.
+// Runners are handed out one at a time to the runner set with the smallest dominant share, so every
+// runner set gets an equal share of its most demanded resource before any set gets more.
+// Priority is only used to break ties.
+func (a *Allocator) AllocateMaxMinFairness(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error) {
+	return a.allocateDominantShares(runnerSets, availableCPUMillis, availableMemoryBytes, availableScalar, func(*RunnerSetResources) float64 {
+		return 1
+	}), nil
+}
+
+// AllocateWeightedDRF calculates maxRunners using Dominant Resource Fairness weighted by priority
+// Unlike AllocateFairShare, CPU and memory are not split independently: a CPU-heavy runner set only
+// competes for its CPU share and leaves memory to memory-heavy runner sets, so mixed workloads use
+// more of the capacity. Each runner set's dominant share is divided by its priority weight, so a
+// runner set with priority 3 ends up with three times the dominant share of one with priority 1.
+// Capacity a runner set can't use is filled progressively by the others.
+func (a *Allocator) AllocateWeightedDRF(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error) {
+	return a.allocateDominantShares(runnerSets, availableCPUMillis, availableMemoryBytes, availableScalar, priorityWeight), nil
+}
+
+// allocateDominantShares hands out runners one at a time to the runner set with the smallest weighted dominant share
+// (progressive filling), until no runner set below its configured max fits into the remaining capacity
+func (a *Allocator) allocateDominantShares(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources, weight func(*RunnerSetResources) float64) []RunnerSetAllocation {
+	if len(runnerSets) == 0 {
+		return []RunnerSetAllocation{}
+	}
+
+	a.logger.Debug("starting dominant resource fairness allocation",
+		"available_cpu_millis", availableCPUMillis,
+		"available_memory_bytes", availableMemoryBytes,
+		"available_scalar", availableScalar,
+		"runner_sets", len(runnerSets))
+
+	// Sort by priority so ties in dominant share go to the higher priority runner set
+	sortedRunnerSets := make([]*RunnerSetResources, len(runnerSets))
+	copy(sortedRunnerSets, runnerSets)
+	sort.Slice(sortedRunnerSets, func(i, j int) bool {
+		return higherPriority(sortedRunnerSets[i], sortedRunnerSets[j])
+	})
+
+	maxRunners := make(map[types.NamespacedName]int, len(runnerSets))
+	remainingCPU := availableCPUMillis
+	remainingMemory := availableMemoryBytes
+	remainingScalar := availableScalar.clone()
+
+	// Enforce minimum runners guarantee before sharing the rest
+	for _, rs := range sortedRunnerSets {
+		if rs.MinRunners > 0 {
+			maxRunners[rs.Key()] = rs.MinRunners
+			remainingCPU -= int64(rs.MinRunners) * rs.CPUMillis
+			remainingMemory -= int64(rs.MinRunners) * rs.MemoryBytes
+			remainingScalar.add(rs.ScalarResources, -int64(rs.MinRunners))
+		}
+	}
+
+	// Progressive filling: give the next runner to the runner set with the smallest weighted dominant share
+	for {
+		var next *RunnerSetResources
+		nextShare := 0.0
+		for _, rs := range sortedRunnerSets {
+			// Skip if already at its cap (configured max or demand limit)
+			if limit := rs.maxRunnersCap(); limit >= 0 && maxRunners[rs.Key()] >= limit {
+				continue
+			}
+			// Skip if another runner doesn't fit
+			if a.calculateMaxRunners(rs, remainingCPU, remainingMemory, remainingScalar) == 0 {
+				continue
+			}
+
+			share := dominantShare(rs, maxRunners[rs.Key()], availableCPUMillis, availableMemoryBytes, availableScalar) / weight(rs)
+			if next == nil || share < nextShare {
+				next = rs
+				nextShare = share
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		maxRunners[next.Key()]++
+		remainingCPU -= next.CPUMillis
+		remainingMemory -= next.MemoryBytes
+		remainingScalar.add(next.ScalarResources, -1)
+	}
+
+	results := make([]RunnerSetAllocation, 0, len(runnerSets))
+	for _, rs := range runnerSets {
+		a.logger.Debug("dominant resource fairness allocation",
+			"namespace", rs.Namespace,
+			"name", rs.Name,
+			"priority", rs.Priority,
+			"weight", weight(rs),
+			"max_runners", maxRunners[rs.Key()],
+			"dominant_share", dominantShare(rs, maxRunners[rs.Key()], availableCPUMillis, availableMemoryBytes, availableScalar))
+
+		results = append(results, RunnerSetAllocation{
+			Namespace:  rs.Namespace,
+			Name:       rs.Name,
+			MaxRunners: maxRunners[rs.Key()],
+		})
+	}
+
+	a.logger.Debug("dominant resource fairness allocation completed",
+		"remaining_cpu_millis", remainingCPU,
+		"remaining_memory_bytes", remainingMemory,
+		"remaining_scalar", remainingScalar)
+
+	return results
+}
+
+// priorityWeight returns the weight of a runner set in weighted allocations.
+// Priorities below 1 are treated as 1, so every runner set gets a share.
+func priorityWeight(rs *RunnerSetResources) float64 {
+	return float64(max(rs.Priority, 1))
+}
+
+// dominantShare returns the largest of the CPU, memory and extended resource shares that a number of runners
+// of a runner set take from the available capacity
+func dominantShare(rs *RunnerSetResources, runners int, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) float64 {
+	share := 0.0
+	if availableCPUMillis > 0 {
+		share = float64(int64(runners)*rs.CPUMillis) / float64(availableCPUMillis)
+	}
+	if availableMemoryBytes > 0 {
+		share = max(share, float64(int64(runners)*rs.MemoryBytes)/float64(availableMemoryBytes))
+	}
+	for name, request := range rs.ScalarResources {
+		if available := availableScalar[name]; available > 0 {
+			share = max(share, float64(int64(runners)*request)/float64(available))
+		}
+	}
+	return share
+}
+
+// higherPriority reports whether runner set a is allocated before runner set b.
+// Higher priority comes first; ties are broken by namespace and name for deterministic behavior.
+func higherPriority(a, b *RunnerSetResources) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// calculateMaxRunners calculates how many runners of a given spec can fit in the available capacity.
+// Extended resources requested by the runners are additional constraints, runners don't fit without them.
+func (a *Allocator) calculateMaxRunners(rs *RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) int {
 	if rs.CPUMillis <= 0 || rs.MemoryBytes <= 0 {
 		return 0
 	}
@@ -301,6 +472,11 @@ func (a *Allocator) calculateMaxRunners(rs *RunnerSetResources, availableCPUMill
 
 	// Take the minimum (most constrained resource)
 	maxRunners := max(0, min(maxByMemory, maxByCPU))
+	for name, request := range rs.ScalarResources {
+		if request > 0 {
+			maxRunners = max(0, min(maxRunners, availableScalar[name]/request))
+		}
+	}
 
 	return int(maxRunners)
 }
@@ -4,6 +4,8 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestAllocator_Allocate(t *testing.T) {
@@ -140,7 +142,7 @@ func TestAllocator_Allocate(t *testing.T) {
 			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 			allocator := NewAllocator(logger)
 
-			allocations, err := allocator.Allocate(tt.runnerSets, tt.availableCPUMillis, tt.availableMemoryBytes)
+			allocations, err := allocator.Allocate(tt.runnerSets, tt.availableCPUMillis, tt.availableMemoryBytes, nil)
 			if err != nil {
 				t.Fatalf("Allocate() error = %v", err)
 			}
@@ -370,7 +372,7 @@ func TestAllocator_AllocateFairShare(t *testing.T) {
 			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 			allocator := NewAllocator(logger)
 
-			allocations, err := allocator.AllocateFairShare(tt.runnerSets, tt.availableCPUMillis, tt.availableMemoryBytes)
+			allocations, err := allocator.AllocateFairShare(tt.runnerSets, tt.availableCPUMillis, tt.availableMemoryBytes, nil)
 			if err != nil {
 				t.Fatalf("AllocateFairShare() error = %v", err)
 			}
@@ -404,14 +406,554 @@ func TestAllocator_AllocateFairShare(t *testing.T) {
 	}
 }
 
+func TestAllocator_AllocateMaxMinFairness(t *testing.T) {
+	tests := []struct {
+		name                 string
+		runnerSets           []*RunnerSetResources
+		availableCPUMillis   int64
+		availableMemoryBytes int64
+		want                 map[string]int // name -> maxRunners
+	}{
+		{
+			name:                 "no runner sets",
+			runnerSets:           []*RunnerSetResources{},
+			availableCPUMillis:   10000,
+			availableMemoryBytes: 20 * 1024 * 1024 * 1024,
+			want:                 map[string]int{},
+		},
+		{
+			name: "equal shares regardless of priority",
+			runnerSets: []*RunnerSetResources{
+				{Name: "high-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 20},
+				{Name: "low-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   10000,                   // 10 CPUs
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024, // 40Gi
+			want: map[string]int{
+				"high-priority": 5,
+				"low-priority":  5,
+			},
+		},
+		{
+			name: "dominant shares are equalized across resources",
+			runnerSets: []*RunnerSetResources{
+				{Name: "cpu-heavy", CPUMillis: 4000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+				{Name: "memory-heavy", CPUMillis: 1000, MemoryBytes: 4 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   20000,                   // 20 CPUs
+			availableMemoryBytes: 20 * 1024 * 1024 * 1024, // 20Gi
+			want: map[string]int{
+				"cpu-heavy":    4, // 16 CPUs (80% dominant share), 4Gi
+				"memory-heavy": 4, // 4 CPUs, 16Gi (80% dominant share)
+			},
+		},
+		{
+			name: "capped runner set leaves capacity to others",
+			runnerSets: []*RunnerSetResources{
+				{Name: "capped", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 2},
+				{Name: "uncapped", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1},
+			},
+			availableCPUMillis:   10000,
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024,
+			want: map[string]int{
+				"capped":   2,
+				"uncapped": 8,
+			},
+		},
+		{
+			name: "minimum runners are allocated first",
+			runnerSets: []*RunnerSetResources{
+				{Name: "with-min", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, MinRunners: 6, ConfiguredMax: 20},
+				{Name: "without-min", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   8000,
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024,
+			want: map[string]int{
+				"with-min":    6, // Guaranteed minimum
+				"without-min": 2, // Remaining capacity
+			},
+		},
+		{
+			name: "priority breaks ties",
+			runnerSets: []*RunnerSetResources{
+				{Name: "low-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+				{Name: "high-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   3000,
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024,
+			want: map[string]int{
+				"high-priority": 2, // Gets the odd runner
+				"low-priority":  1,
+			},
+		},
+		{
+			name: "small runners fill capacity large runners can't use",
+			runnerSets: []*RunnerSetResources{
+				{Name: "large", CPUMillis: 4000, MemoryBytes: 4 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+				{Name: "small", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   10000,
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024,
+			want: map[string]int{
+				"large": 1, // 40% share, another runner doesn't fit after small takes 4 CPUs
+				"small": 6, // Fills the remaining 6 CPUs
+			},
+		},
+		{
+			name: "runner set without resources gets nothing",
+			runnerSets: []*RunnerSetResources{
+				{Name: "no-resources", Priority: 1, ConfiguredMax: 20},
+				{Name: "runner-set", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   4000,
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024,
+			want: map[string]int{
+				"no-resources": 0,
+				"runner-set":   4,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+			allocator := NewAllocator(logger)
+
+			allocations, err := allocator.AllocateMaxMinFairness(tt.runnerSets, tt.availableCPUMillis, tt.availableMemoryBytes, nil)
+			if err != nil {
+				t.Fatalf("AllocateMaxMinFairness() error = %v", err)
+			}
+
+			got := make(map[string]int)
+			for _, alloc := range allocations {
+				got[alloc.Name] = alloc.MaxRunners
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("got %d allocations, want %d: %v", len(got), len(tt.want), got)
+			}
+			for name, wantMax := range tt.want {
+				if gotMax := got[name]; gotMax != wantMax {
+					t.Errorf("allocation for %s = %v, want %v", name, gotMax, wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocator_AllocateWeightedDRF(t *testing.T) {
+	tests := []struct {
+		name                 string
+		runnerSets           []*RunnerSetResources
+		availableCPUMillis   int64
+		availableMemoryBytes int64
+		want                 map[string]int // name -> maxRunners
+	}{
+		{
+			name:                 "no runner sets",
+			runnerSets:           []*RunnerSetResources{},
+			availableCPUMillis:   10000,
+			availableMemoryBytes: 20 * 1024 * 1024 * 1024,
+			want:                 map[string]int{},
+		},
+		{
+			name: "dominant shares are weighted by priority",
+			runnerSets: []*RunnerSetResources{
+				{Name: "high-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 3, ConfiguredMax: 20},
+				{Name: "low-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   8000,                    // 8 CPUs
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024, // 40Gi
+			want: map[string]int{
+				"high-priority": 6, // 3x the share of low-priority
+				"low-priority":  2,
+			},
+		},
+		{
+			name: "zero priority is treated as weight 1",
+			runnerSets: []*RunnerSetResources{
+				{Name: "runner-set-a", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 0, ConfiguredMax: 20},
+				{Name: "runner-set-b", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 0, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   8000,
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024,
+			want: map[string]int{
+				"runner-set-a": 4,
+				"runner-set-b": 4,
+			},
+		},
+		{
+			name: "mixed CPU-heavy and memory-heavy workloads",
+			runnerSets: []*RunnerSetResources{
+				{Name: "cpu-heavy", CPUMillis: 3000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 3, ConfiguredMax: 20},
+				{Name: "memory-heavy", CPUMillis: 1000, MemoryBytes: 3 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   16000,                   // 16 CPUs
+			availableMemoryBytes: 16 * 1024 * 1024 * 1024, // 16Gi
+			want: map[string]int{
+				"cpu-heavy":    4, // 12 CPUs, 4Gi
+				"memory-heavy": 4, // 4 CPUs, 12Gi
+			},
+		},
+		{
+			name: "capacity of capped runner sets is filled by others",
+			runnerSets: []*RunnerSetResources{
+				{Name: "high-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 2},
+				{Name: "low-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   10000,
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024,
+			want: map[string]int{
+				"high-priority": 2, // Capped at ConfiguredMax
+				"low-priority":  8, // Gets the rest
+			},
+		},
+		{
+			name: "minimum runners are guaranteed",
+			runnerSets: []*RunnerSetResources{
+				{Name: "high-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 20},
+				{Name: "low-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, MinRunners: 3, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   5000,
+			availableMemoryBytes: 40 * 1024 * 1024 * 1024,
+			want: map[string]int{
+				"high-priority": 2, // Remaining capacity after the minimum
+				"low-priority":  3, // Guaranteed minimum
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+			allocator := NewAllocator(logger)
+
+			allocations, err := allocator.AllocateWeightedDRF(tt.runnerSets, tt.availableCPUMillis, tt.availableMemoryBytes, nil)
+			if err != nil {
+				t.Fatalf("AllocateWeightedDRF() error = %v", err)
+			}
+
+			got := make(map[string]int)
+			for _, alloc := range allocations {
+				got[alloc.Name] = alloc.MaxRunners
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("got %d allocations, want %d: %v", len(got), len(tt.want), got)
+			}
+			for name, wantMax := range tt.want {
+				if gotMax := got[name]; gotMax != wantMax {
+					t.Errorf("allocation for %s = %v, want %v", name, gotMax, wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocator_WeightedDRFUtilization(t *testing.T) {
+	// Fair share splits CPU and memory independently by priority weight. In the first case, the CPU-heavy runner set with
+	// priority 3 gets 75% of both resources but can only use its CPU share, while the memory-heavy
+	// runner set is limited by its 25% memory share, leaving half of the memory unused.
+	tests := []struct {
+		name                 string
+		runnerSets           []*RunnerSetResources
+		availableCPUMillis   int64
+		availableMemoryBytes int64
+	}{
+		{
+			name: "CPU-heavy and memory-heavy runner sets",
+			runnerSets: []*RunnerSetResources{
+				{Name: "cpu-heavy", CPUMillis: 3000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 3, ConfiguredMax: 20},
+				{Name: "memory-heavy", CPUMillis: 1000, MemoryBytes: 3 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			availableCPUMillis:   16000,
+			availableMemoryBytes: 16 * 1024 * 1024 * 1024,
+		},
+		{
+			name: "three runner sets with different resource profiles",
+			runnerSets: []*RunnerSetResources{
+				{Name: "compile", CPUMillis: 4000, MemoryBytes: 2 * 1024 * 1024 * 1024, Priority: 5, ConfiguredMax: 50},
+				{Name: "integration", CPUMillis: 1000, MemoryBytes: 8 * 1024 * 1024 * 1024, Priority: 2, ConfiguredMax: 50},
+				{Name: "lint", CPUMillis: 500, MemoryBytes: 512 * 1024 * 1024, Priority: 1, ConfiguredMax: 50},
+			},
+			availableCPUMillis:   64000,
+			availableMemoryBytes: 128 * 1024 * 1024 * 1024,
+		},
+	}
+
+	// utilization returns the fraction of CPU and memory used by the allocations
+	utilization := func(runnerSets []*RunnerSetResources, allocations []RunnerSetAllocation, availableCPUMillis, availableMemoryBytes int64) (float64, float64) {
+		usedCPU, usedMemory := int64(0), int64(0)
+		for _, alloc := range allocations {
+			for _, rs := range runnerSets {
+				if rs.Key() == alloc.Key() {
+					usedCPU += int64(alloc.MaxRunners) * rs.CPUMillis
+					usedMemory += int64(alloc.MaxRunners) * rs.MemoryBytes
+				}
+			}
+		}
+		if usedCPU > availableCPUMillis || usedMemory > availableMemoryBytes {
+			t.Errorf("allocations exceed capacity: %d/%d CPU, %d/%d memory", usedCPU, availableCPUMillis, usedMemory, availableMemoryBytes)
+		}
+		return float64(usedCPU) / float64(availableCPUMillis), float64(usedMemory) / float64(availableMemoryBytes)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocator := NewAllocator(testLogger())
+
+			fairShare, err := allocator.AllocateFairShare(tt.runnerSets, tt.availableCPUMillis, tt.availableMemoryBytes, nil)
+			if err != nil {
+				t.Fatalf("AllocateFairShare() error = %v", err)
+			}
+			drf, err := allocator.AllocateWeightedDRF(tt.runnerSets, tt.availableCPUMillis, tt.availableMemoryBytes, nil)
+			if err != nil {
+				t.Fatalf("AllocateWeightedDRF() error = %v", err)
+			}
+
+			fairShareCPU, fairShareMemory := utilization(tt.runnerSets, fairShare, tt.availableCPUMillis, tt.availableMemoryBytes)
+			drfCPU, drfMemory := utilization(tt.runnerSets, drf, tt.availableCPUMillis, tt.availableMemoryBytes)
+
+			t.Logf("fair share: %.0f%% CPU, %.0f%% memory; weighted DRF: %.0f%% CPU, %.0f%% memory",
+				fairShareCPU*100, fairShareMemory*100, drfCPU*100, drfMemory*100)
+
+			if drfCPU+drfMemory <= fairShareCPU+fairShareMemory {
+				t.Errorf("weighted DRF utilization (%.2f CPU, %.2f memory) is not better than fair share (%.2f CPU, %.2f memory)",
+					drfCPU, drfMemory, fairShareCPU, fairShareMemory)
+			}
+		})
+	}
+}
+
+func TestAllocator_NamespaceAwareIdentity(t *testing.T) {
+	// Runner sets with the same name in different namespaces must not clobber each other
+	newRunnerSets := func() []*RunnerSetResources {
+		return []*RunnerSetResources{
+			{Namespace: "team-b", Name: "ci-default", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			{Namespace: "team-a", Name: "ci-default", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 2},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		allocate func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error)
+		want     map[string]int // namespace/name -> maxRunners
+	}{
+		{
+			name: "strict priority breaks ties by namespace",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.Allocate(runnerSets, 10000, 20*1024*1024*1024, nil)
+			},
+			want: map[string]int{
+				"team-a/ci-default": 2, // First by namespace, capped at 2
+				"team-b/ci-default": 8, // Remaining 8 CPUs
+			},
+		},
+		{
+			name: "fair share redistribution updates the right runner set",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.AllocateFairShare(runnerSets, 10000, 20*1024*1024*1024, nil)
+			},
+			want: map[string]int{
+				"team-a/ci-default": 2, // 5 from fair share, capped at 2
+				"team-b/ci-default": 8, // 5 from fair share + 3 from redistribution
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+			allocator := NewAllocator(logger)
+
+			allocations, err := tt.allocate(allocator, newRunnerSets())
+			if err != nil {
+				t.Fatalf("allocate error = %v", err)
+			}
+
+			got := make(map[string]int)
+			for _, alloc := range allocations {
+				got[alloc.Key().String()] = alloc.MaxRunners
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("got %d allocations, want %d: %v", len(got), len(tt.want), got)
+			}
+			for key, wantMax := range tt.want {
+				if gotMax := got[key]; gotMax != wantMax {
+					t.Errorf("allocation for %s = %v, want %v", key, gotMax, wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocator_DemandLimits(t *testing.T) {
+	// An idle runner set limited to its demand leaves its share to a busy one
+	newRunnerSets := func() []*RunnerSetResources {
+		return []*RunnerSetResources{
+			{Name: "idle", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 10, MinRunners: 1, ConfiguredMax: 20, DemandMax: intPtr(1)},
+			{Name: "busy", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		allocate func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error)
+	}{
+		{
+			name: "strict priority",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.Allocate(runnerSets, 10000, 20*1024*1024*1024, nil)
+			},
+		},
+		{
+			name: "fair share",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.AllocateFairShare(runnerSets, 10000, 20*1024*1024*1024, nil)
+			},
+		},
+		{
+			name: "max-min fairness",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.AllocateMaxMinFairness(runnerSets, 10000, 20*1024*1024*1024, nil)
+			},
+		},
+		{
+			name: "weighted DRF",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.AllocateWeightedDRF(runnerSets, 10000, 20*1024*1024*1024, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocations, err := tt.allocate(NewAllocator(testLogger()), newRunnerSets())
+			if err != nil {
+				t.Fatalf("allocate error = %v", err)
+			}
+
+			want := map[string]int{
+				"idle": 1, // Only its minimum runners
+				"busy": 9, // Everything else
+			}
+			for _, alloc := range allocations {
+				if alloc.MaxRunners != want[alloc.Name] {
+					t.Errorf("allocation for %s = %v, want %v", alloc.Name, alloc.MaxRunners, want[alloc.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestAllocator_ExtendedResources(t *testing.T) {
+	// The GPU runner set has the highest priority, but only 3 GPUs are available
+	newRunnerSets := func() []*RunnerSetResources {
+		return []*RunnerSetResources{
+			{Name: "gpu", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, ScalarResources: ScalarResources{"nvidia.com/gpu": 1}, Priority: 10, ConfiguredMax: 20},
+			{Name: "cpu", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+		}
+	}
+	availableScalar := ScalarResources{"nvidia.com/gpu": 3}
+
+	tests := []struct {
+		name     string
+		allocate func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error)
+	}{
+		{
+			name: "strict priority",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.Allocate(runnerSets, 10000, 20*1024*1024*1024, availableScalar)
+			},
+		},
+		{
+			name: "fair share",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.AllocateFairShare(runnerSets, 10000, 20*1024*1024*1024, availableScalar)
+			},
+		},
+		{
+			name: "max-min fairness",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.AllocateMaxMinFairness(runnerSets, 10000, 20*1024*1024*1024, availableScalar)
+			},
+		},
+		{
+			name: "weighted DRF",
+			allocate: func(a *Allocator, runnerSets []*RunnerSetResources) ([]RunnerSetAllocation, error) {
+				return a.AllocateWeightedDRF(runnerSets, 10000, 20*1024*1024*1024, availableScalar)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocations, err := tt.allocate(NewAllocator(testLogger()), newRunnerSets())
+			if err != nil {
+				t.Fatalf("allocate error = %v", err)
+			}
+
+			want := map[string]int{
+				"gpu": 3, // One runner per GPU
+				"cpu": 7, // The CPUs left over
+			}
+			for _, alloc := range allocations {
+				if alloc.MaxRunners != want[alloc.Name] {
+					t.Errorf("allocation for %s = %v, want %v", alloc.Name, alloc.MaxRunners, want[alloc.Name])
+				}
+			}
+		})
+	}
+}
+
 func TestAllocator_calculateMaxRunners(t *testing.T) {
 	tests := []struct {
 		name                 string
 		rs                   *RunnerSetResources
 		availableCPUMillis   int64
 		availableMemoryBytes int64
+		availableScalar      ScalarResources
 		want                 int
 	}{
+		{
+			name: "extended resource constrained",
+			rs: &RunnerSetResources{
+				Name:            "test",
+				CPUMillis:       1000,
+				MemoryBytes:     1 * 1024 * 1024 * 1024,
+				ScalarResources: ScalarResources{"nvidia.com/gpu": 2},
+			},
+			availableCPUMillis:   10000,
+			availableMemoryBytes: 100 * 1024 * 1024 * 1024,
+			availableScalar:      ScalarResources{"nvidia.com/gpu": 5}, // Can fit 2 runners by GPUs
+			want:                 2,
+		},
+		{
+			name: "ephemeral storage constrained",
+			rs: &RunnerSetResources{
+				Name:            "test",
+				CPUMillis:       1000,
+				MemoryBytes:     1 * 1024 * 1024 * 1024,
+				ScalarResources: ScalarResources{corev1.ResourceEphemeralStorage: 20 * 1024 * 1024 * 1024},
+			},
+			availableCPUMillis:   10000,
+			availableMemoryBytes: 100 * 1024 * 1024 * 1024,
+			availableScalar:      ScalarResources{corev1.ResourceEphemeralStorage: 70 * 1024 * 1024 * 1024}, // Can fit 3 runners by storage
+			want:                 3,
+		},
+		{
+			name: "extended resource not available",
+			rs: &RunnerSetResources{
+				Name:            "test",
+				CPUMillis:       1000,
+				MemoryBytes:     1 * 1024 * 1024 * 1024,
+				ScalarResources: ScalarResources{"nvidia.com/gpu": 1},
+			},
+			availableCPUMillis:   10000,
+			availableMemoryBytes: 100 * 1024 * 1024 * 1024,
+			want:                 0,
+		},
 		{
 			name: "CPU constrained",
 			rs: &RunnerSetResources{
@@ -515,7 +1057,7 @@ func TestAllocator_calculateMaxRunners(t *testing.T) {
 			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 			allocator := NewAllocator(logger)
 
-			got := allocator.calculateMaxRunners(tt.rs, tt.availableCPUMillis, tt.availableMemoryBytes)
+			got := allocator.calculateMaxRunners(tt.rs, tt.availableCPUMillis, tt.availableMemoryBytes, tt.availableScalar)
 			if got != tt.want {
 				t.Errorf("calculateMaxRunners() = %v, want %v", got, tt.want)
 			}
@@ -4,27 +4,71 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	resourcehelper "k8s.io/component-helpers/resource"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
 )
 
+// toBeDeletedTaint is the taint the cluster-autoscaler adds to nodes it is draining and removing
+const toBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
+
+// Reasons nodes are dropped from capacity
+const (
+	nodeDropReasonNotReady    = "not_ready"
+	nodeDropReasonCordoned    = "cordoned"
+	nodeDropReasonToBeDeleted = "to_be_deleted"
+	nodeDropReasonTainted     = "tainted"
+	nodeDropReasonNotSelected = "not_selected"
+	nodeDropReasonExcluded    = "excluded"
+)
+
+// nodeDropReasons are all reasons nodes are dropped from capacity, in the order they are checked
+var nodeDropReasons = []string{
+	nodeDropReasonNotReady,
+	nodeDropReasonCordoned,
+	nodeDropReasonToBeDeleted,
+	nodeDropReasonTainted,
+	nodeDropReasonNotSelected,
+	nodeDropReasonExcluded,
+}
+
 // CapacityCalculator calculates available cluster capacity
 type CapacityCalculator struct {
-	client           client.Client
-	logger           *slog.Logger
-	cpuBufferPercent int
-	memBufferPercent int
+	client client.Client
+	logger *slog.Logger
+}
+
+// CapacityBuffers are the safety buffers kept free for workloads other than runners.
+//
+// Absolute reserves are subtracted from free capacity first, then the percentage buffers are
+// reserved from what remains. Node reserves are kept free on every node, the cluster reserves
+// across the cluster on top of them.
+type CapacityBuffers struct {
+	CPUPercent    int
+	MemoryPercent int
+
+	CPUReserveMillis   int64
+	MemoryReserveBytes int64
+
+	NodeCPUReserveMillis   int64
+	NodeMemoryReserveBytes int64
+
+	// ReserveUnscheduled keeps the requests of pods not bound to a node yet free across the cluster,
+	// on top of the cluster reserves
+	ReserveUnscheduled bool
 }
 
 // NewCapacityCalculator creates a new capacity calculator
-func NewCapacityCalculator(client client.Client, logger *slog.Logger, cpuBufferPercent, memBufferPercent int) *CapacityCalculator {
+func NewCapacityCalculator(client client.Client, logger *slog.Logger) *CapacityCalculator {
 	return &CapacityCalculator{
-		client:           client,
-		logger:           logger,
-		cpuBufferPercent: cpuBufferPercent,
-		memBufferPercent: memBufferPercent,
+		client: client,
+		logger: logger,
 	}
 }
 
@@ -36,93 +80,352 @@ type ClusterCapacity struct {
 	UsedMemoryBytes      int64
 	AvailableCPUMillis   int64
 	AvailableMemoryBytes int64
+
+	// ExcludedCPUMillis and ExcludedMemoryBytes are the requests of runner pods, which aren't counted as used
+	ExcludedCPUMillis   int64
+	ExcludedMemoryBytes int64
+
+	// ReservedCPUMillis and ReservedMemoryBytes are the free capacity kept free by reserves and buffers
+	ReservedCPUMillis   int64
+	ReservedMemoryBytes int64
+
+	// UnscheduledCPUMillis and UnscheduledMemoryBytes are the requests of pods not bound to a node yet,
+	// which aren't counted as used. They're only kept free with CapacityBuffers.ReserveUnscheduled.
+	UnscheduledCPUMillis   int64
+	UnscheduledMemoryBytes int64
+	UnscheduledScalar      ScalarResources
+
+	// Pods, ephemeral storage, extended resources and hugepages offered by the nodes. Reserves and buffers
+	// don't apply to them, only reserved unscheduled pods are kept free.
+	TotalScalar     ScalarResources
+	UsedScalar      ScalarResources
+	AvailableScalar ScalarResources
+
+	// Nodes contains the capacity of each ready node, used for bin-packing runners
+	// and for matching runner sets to the nodes they can be scheduled onto
+	Nodes []NodeCapacity
+
+	// DroppedNodes maps the nodes not counted toward capacity to the reason they were dropped, e.g. "cordoned"
+	DroppedNodes map[string]string
+}
+
+// NodeCapacity represents the capacity of a single node
+type NodeCapacity struct {
+	Name                   string
+	Labels                 map[string]string
+	Taints                 []corev1.Taint
+	AllocatableCPUMillis   int64
+	AllocatableMemoryBytes int64
+	UsedCPUMillis          int64
+	UsedMemoryBytes        int64
+	AvailableCPUMillis     int64
+	AvailableMemoryBytes   int64
+
+	AllocatableScalar ScalarResources
+	UsedScalar        ScalarResources
+	AvailableScalar   ScalarResources
 }
 
-// Calculate calculates the available cluster capacity with safety buffers
-func (c *CapacityCalculator) Calculate(ctx context.Context) (*ClusterCapacity, error) {
-	// Get total cluster capacity from nodes
-	totalCPU, totalMemory, nodeCount, err := c.getClusterCapacity(ctx)
+// podUsage is the resource usage of pods, split into counted pods bound to a capacity node, excluded (runner) pods
+// and unscheduled pods. Pods bound to nodes not counted toward capacity are ignored.
+type podUsage struct {
+	cpuMillis              int64
+	memoryBytes            int64
+	excludedCPUMillis      int64
+	excludedMemoryBytes    int64
+	unscheduledCPUMillis   int64
+	unscheduledMemoryBytes int64
+	podCount               int
+	excludedCount          int
+	unscheduledCount       int
+	ignoredCount           int
+
+	// Pods, ephemeral storage, extended resources and hugepages requested by counted and unscheduled pods
+	scalar            ScalarResources
+	unscheduledScalar ScalarResources
+
+	// Usage of counted pods by the node they are bound to
+	nodeCPUMillis   map[string]int64
+	nodeMemoryBytes map[string]int64
+	nodeScalar      map[string]ScalarResources
+}
+
+// Calculate calculates the available cluster capacity of the nodes passing the node filter with safety buffers.
+// Both are passed per call, so they come from the same configuration as the rest of a reconciliation.
+func (c *CapacityCalculator) Calculate(ctx context.Context, buffers CapacityBuffers, filter config.NodeFilterConfig) (*ClusterCapacity, error) {
+	// Get allocatable capacity of each node passing the node filter
+	nodes, droppedNodes, err := c.getClusterCapacity(ctx, &filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cluster capacity: %w", err)
 	}
 
-	// Get current resource usage from pods
-	usedCPU, usedMemory, excludedCPU, excludedMemory, podCount, excludedCount, err := c.getCurrentUsage(ctx)
+	// Get current resource usage from pods bound to these nodes
+	usage, err := c.getCurrentUsage(ctx, nodes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current usage: %w", err)
 	}
 
 	// Log detailed breakdown
 	c.logger.Info("capacity breakdown",
-		"nodes", nodeCount,
-		"pods_counted", podCount,
-		"pods_excluded", excludedCount,
-		"excluded_cpu_millis", excludedCPU,
-		"excluded_cpu_cores", float64(excludedCPU)/1000,
-		"excluded_memory_bytes", excludedMemory,
-		"excluded_memory_gb", float64(excludedMemory)/(1024*1024*1024))
-
-	// Calculate available capacity with safety buffer
-	rawAvailableCPU := max(totalCPU-usedCPU, 0)
-	rawAvailableMemory := max(totalMemory-usedMemory, 0)
-
-	// Apply safety buffer
-	availableCPU := (rawAvailableCPU * int64(100-c.cpuBufferPercent)) / 100
-	availableMemory := (rawAvailableMemory * int64(100-c.memBufferPercent)) / 100
+		"nodes", len(nodes),
+		"nodes_dropped", len(droppedNodes),
+		"dropped_nodes", droppedNodes,
+		"pods_counted", usage.podCount,
+		"pods_excluded", usage.excludedCount,
+		"pods_unscheduled", usage.unscheduledCount,
+		"pods_ignored", usage.ignoredCount,
+		"unscheduled_cpu_millis", usage.unscheduledCPUMillis,
+		"unscheduled_memory_bytes", usage.unscheduledMemoryBytes,
+		"excluded_cpu_millis", usage.excludedCPUMillis,
+		"excluded_cpu_cores", float64(usage.excludedCPUMillis)/1000,
+		"excluded_memory_bytes", usage.excludedMemoryBytes,
+		"excluded_memory_gb", float64(usage.excludedMemoryBytes)/(1024*1024*1024))
+
+	// Calculate per-node free capacity with node reserves
+	var totalCPU int64
+	var totalMemory int64
+	var nodeReservedCPU int64
+	var nodeReservedMemory int64
+	totalScalar := ScalarResources{}
+	freeCPU := make([]int64, len(nodes))
+	freeMemory := make([]int64, len(nodes))
+	for i := range nodes {
+		node := &nodes[i]
+		node.UsedCPUMillis = usage.nodeCPUMillis[node.Name]
+		node.UsedMemoryBytes = usage.nodeMemoryBytes[node.Name]
+		node.UsedScalar = usage.nodeScalar[node.Name]
+		node.AvailableScalar = availableScalar(node.AllocatableScalar, node.UsedScalar)
+
+		// A node can't keep more free than it has free
+		freeCPU[i] = max(node.AllocatableCPUMillis-node.UsedCPUMillis, 0)
+		freeMemory[i] = max(node.AllocatableMemoryBytes-node.UsedMemoryBytes, 0)
+		reservedCPU := min(buffers.NodeCPUReserveMillis, freeCPU[i])
+		reservedMemory := min(buffers.NodeMemoryReserveBytes, freeMemory[i])
+		nodeReservedCPU += reservedCPU
+		nodeReservedMemory += reservedMemory
+		freeCPU[i] -= reservedCPU
+		freeMemory[i] -= reservedMemory
+
+		totalCPU += node.AllocatableCPUMillis
+		totalMemory += node.AllocatableMemoryBytes
+		totalScalar.add(node.AllocatableScalar, 1)
+	}
+
+	// Calculate available capacity with reserves and safety buffer. The cluster reserves, and optionally
+	// the requests of unscheduled pods, are kept free on top of what the nodes reserve.
+	clusterReservedCPU := buffers.CPUReserveMillis
+	clusterReservedMemory := buffers.MemoryReserveBytes
+	unavailableScalar := usage.scalar.clone()
+	if buffers.ReserveUnscheduled {
+		clusterReservedCPU += usage.unscheduledCPUMillis
+		clusterReservedMemory += usage.unscheduledMemoryBytes
+		unavailableScalar.add(usage.unscheduledScalar, 1)
+	}
+	rawAvailableCPU := max(totalCPU-usage.cpuMillis, 0)
+	rawAvailableMemory := max(totalMemory-usage.memoryBytes, 0)
+	availableCPU := buffers.applyCPUBuffer(max(rawAvailableCPU-nodeReservedCPU-clusterReservedCPU, 0))
+	availableMemory := buffers.applyMemoryBuffer(max(rawAvailableMemory-nodeReservedMemory-clusterReservedMemory, 0))
+
+	// The cluster reserves and reserved unscheduled pods are kept free on the nodes too, spread over them by their
+	// free capacity, so runners bin-packed onto nodes or given a node pool's capacity can't use what the cluster-wide
+	// figures keep free. The safety buffer applies last, as it does cluster-wide.
+	clusterReservedNodeCPU := spreadReserve(clusterReservedCPU, freeCPU)
+	clusterReservedNodeMemory := spreadReserve(clusterReservedMemory, freeMemory)
+	if buffers.ReserveUnscheduled {
+		for _, name := range usage.unscheduledScalar.names() {
+			free := make([]int64, len(nodes))
+			for i := range nodes {
+				free[i] = nodes[i].AvailableScalar[name]
+			}
+			for i, share := range spreadReserve(usage.unscheduledScalar[name], free) {
+				if share > 0 {
+					nodes[i].AvailableScalar[name] -= share
+				}
+			}
+		}
+	}
+	for i := range nodes {
+		node := &nodes[i]
+		node.AvailableCPUMillis = buffers.applyCPUBuffer(freeCPU[i] - clusterReservedNodeCPU[i])
+		node.AvailableMemoryBytes = buffers.applyMemoryBuffer(freeMemory[i] - clusterReservedNodeMemory[i])
+
+		c.logger.Debug("node capacity",
+			"node", node.Name,
+			"allocatable_cpu_millis", node.AllocatableCPUMillis,
+			"allocatable_memory_bytes", node.AllocatableMemoryBytes,
+			"used_cpu_millis", node.UsedCPUMillis,
+			"used_memory_bytes", node.UsedMemoryBytes,
+			"available_cpu_millis", node.AvailableCPUMillis,
+			"available_memory_bytes", node.AvailableMemoryBytes,
+			"available_scalar", node.AvailableScalar)
+	}
 
 	return &ClusterCapacity{
-		TotalCPUMillis:       totalCPU,
-		TotalMemoryBytes:     totalMemory,
-		UsedCPUMillis:        usedCPU,
-		UsedMemoryBytes:      usedMemory,
-		AvailableCPUMillis:   availableCPU,
-		AvailableMemoryBytes: availableMemory,
+		TotalCPUMillis:         totalCPU,
+		TotalMemoryBytes:       totalMemory,
+		UsedCPUMillis:          usage.cpuMillis,
+		UsedMemoryBytes:        usage.memoryBytes,
+		AvailableCPUMillis:     availableCPU,
+		AvailableMemoryBytes:   availableMemory,
+		ExcludedCPUMillis:      usage.excludedCPUMillis,
+		ExcludedMemoryBytes:    usage.excludedMemoryBytes,
+		ReservedCPUMillis:      rawAvailableCPU - availableCPU,
+		ReservedMemoryBytes:    rawAvailableMemory - availableMemory,
+		UnscheduledCPUMillis:   usage.unscheduledCPUMillis,
+		UnscheduledMemoryBytes: usage.unscheduledMemoryBytes,
+		UnscheduledScalar:      usage.unscheduledScalar,
+		TotalScalar:            totalScalar,
+		UsedScalar:             usage.scalar,
+		AvailableScalar:        availableScalar(totalScalar, unavailableScalar),
+		Nodes:                  nodes,
+		DroppedNodes:           droppedNodes,
 	}, nil
 }
 
-// getClusterCapacity gets the total allocatable resources from all nodes
-func (c *CapacityCalculator) getClusterCapacity(ctx context.Context) (cpuMillis int64, memoryBytes int64, nodeCount int, err error) {
+// spreadReserve splits a reserve over nodes in proportion to their free amount. Shares are rounded so they add up
+// to the reserve, and a node never keeps more free than it has free.
+func spreadReserve(reserve int64, free []int64) []int64 {
+	shares := make([]int64, len(free))
+	var totalFree int64
+	for _, amount := range free {
+		totalFree += amount
+	}
+	if reserve <= 0 || totalFree <= 0 {
+		return shares
+	}
+	if reserve >= totalFree {
+		copy(shares, free)
+		return shares
+	}
+
+	// Rounding the cumulative share instead of each share keeps the rounding errors from adding up
+	var cumulativeFree, assigned int64
+	for i, amount := range free {
+		cumulativeFree += amount
+		cumulativeShare := int64(math.Ceil(float64(reserve) * float64(cumulativeFree) / float64(totalFree)))
+		shares[i] = min(cumulativeShare-assigned, amount)
+		assigned += shares[i]
+	}
+	return shares
+}
+
+// availableScalar returns the allocatable scalar resources not used by pods
+func availableScalar(allocatable, used ScalarResources) ScalarResources {
+	available := make(ScalarResources, len(allocatable))
+	for name, amount := range allocatable {
+		available[name] = max(amount-used[name], 0)
+	}
+	return available
+}
+
+// applyCPUBuffer reserves the configured CPU safety buffer from free CPU
+func (b *CapacityBuffers) applyCPUBuffer(cpuMillis int64) int64 {
+	return (cpuMillis * int64(100-b.CPUPercent)) / 100
+}
+
+// applyMemoryBuffer reserves the configured memory safety buffer from free memory
+func (b *CapacityBuffers) applyMemoryBuffer(memoryBytes int64) int64 {
+	return (memoryBytes * int64(100-b.MemoryPercent)) / 100
+}
+
+// getClusterCapacity gets the allocatable resources (what can actually be scheduled) of all ready nodes
+// passing the node filter. Dropped nodes are returned by name with the reason they were dropped.
+func (c *CapacityCalculator) getClusterCapacity(ctx context.Context, filter *config.NodeFilterConfig) ([]NodeCapacity, map[string]string, error) {
+	include, err := labels.Parse(filter.LabelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid node label selector: %w", err)
+	}
+	exclude, err := labels.Parse(filter.ExcludeLabelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid exclude node label selector: %w", err)
+	}
+	// An empty selector matches all nodes, but must exclude none
+	if filter.ExcludeLabelSelector == "" {
+		exclude = labels.Nothing()
+	}
+
 	nodeList := &corev1.NodeList{}
 	if err := c.client.List(ctx, nodeList); err != nil {
-		return 0, 0, 0, fmt.Errorf("failed to list nodes: %w", err)
+		return nil, nil, fmt.Errorf("failed to list nodes: %w", err)
 	}
 
-	var totalCPUMillis int64
-	var totalMemoryBytes int64
-	readyNodes := 0
-
+	nodes := make([]NodeCapacity, 0, len(nodeList.Items))
+	dropped := map[string]string{}
 	for _, node := range nodeList.Items {
-		// Skip nodes that are not ready
-		if !isNodeReady(node) {
+		if reason := nodeDropReason(node, filter, include, exclude); reason != "" {
+			dropped[node.Name] = reason
 			continue
 		}
-		readyNodes++
 
-		// Get allocatable resources (what can actually be scheduled)
 		cpu := node.Status.Allocatable[corev1.ResourceCPU]
 		memory := node.Status.Allocatable[corev1.ResourceMemory]
 
-		totalCPUMillis += cpu.MilliValue()
-		totalMemoryBytes += memory.Value()
+		nodes = append(nodes, NodeCapacity{
+			Name:                   node.Name,
+			Labels:                 node.Labels,
+			Taints:                 node.Spec.Taints,
+			AllocatableCPUMillis:   cpu.MilliValue(),
+			AllocatableMemoryBytes: memory.Value(),
+			AllocatableScalar:      newScalarResources(node.Status.Allocatable),
+		})
 	}
 
-	return totalCPUMillis, totalMemoryBytes, readyNodes, nil
+	return nodes, dropped, nil
+}
+
+// nodeDropReason returns why a node isn't counted toward capacity, or an empty string if it is
+func nodeDropReason(node corev1.Node, filter *config.NodeFilterConfig, include, exclude labels.Selector) string {
+	switch {
+	case !isNodeReady(node):
+		return nodeDropReasonNotReady
+	case filter.ExcludeCordoned && node.Spec.Unschedulable:
+		return nodeDropReasonCordoned
+	case filter.ExcludeToBeDeleted && hasTaint(node, func(taint corev1.Taint) bool { return taint.Key == toBeDeletedTaint }):
+		return nodeDropReasonToBeDeleted
+	case filter.ExcludeTainted && hasTaint(node, func(taint corev1.Taint) bool {
+		return taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute
+	}):
+		return nodeDropReasonTainted
+	case !include.Matches(labels.Set(node.Labels)):
+		return nodeDropReasonNotSelected
+	case exclude.Matches(labels.Set(node.Labels)):
+		return nodeDropReasonExcluded
+	}
+	return ""
+}
+
+// hasTaint reports whether a node has a taint matching the predicate
+func hasTaint(node corev1.Node, matches func(taint corev1.Taint) bool) bool {
+	for _, taint := range node.Spec.Taints {
+		if matches(taint) {
+			return true
+		}
+	}
+	return false
 }
 
 // getCurrentUsage gets the current resource usage from all pods except runner pods
-// We exclude runner pods because we're dynamically managing their capacity
-func (c *CapacityCalculator) getCurrentUsage(ctx context.Context) (cpuMillis, memoryBytes, excludedCPU, excludedMemory int64, podCount, excludedCount int, err error) {
+// We exclude runner pods because we're dynamically managing their capacity.
+// Usage is attributed by spec.nodeName, so only pods bound to the given nodes are counted as used,
+// the same nodes total capacity is summed from. Pods not bound to a node yet are counted separately.
+func (c *CapacityCalculator) getCurrentUsage(ctx context.Context, nodes []NodeCapacity) (*podUsage, error) {
 	podList := &corev1.PodList{}
 	if err := c.client.List(ctx, podList); err != nil {
-		return 0, 0, 0, 0, 0, 0, fmt.Errorf("failed to list pods: %w", err)
+		return nil, fmt.Errorf("failed to list pods: %w", err)
 	}
 
-	var totalCPUMillis int64
-	var totalMemoryBytes int64
-	var excludedCPUMillis int64
-	var excludedMemoryBytes int64
-	countedPods := 0
-	excludedPods := 0
+	capacityNodes := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		capacityNodes[node.Name] = struct{}{}
+	}
+
+	usage := &podUsage{
+		nodeCPUMillis:     map[string]int64{},
+		nodeMemoryBytes:   map[string]int64{},
+		scalar:            ScalarResources{},
+		unscheduledScalar: ScalarResources{},
+		nodeScalar:        map[string]ScalarResources{},
+	}
 
 	for _, pod := range podList.Items {
 		// Skip terminated pods
@@ -131,29 +434,57 @@ func (c *CapacityCalculator) getCurrentUsage(ctx context.Context) (cpuMillis, me
 		}
 
 		// Calculate this pod's resources
-		var podCPU int64
-		var podMemory int64
-		for _, container := range pod.Spec.Containers {
-			cpu := container.Resources.Requests[corev1.ResourceCPU]
-			memory := container.Resources.Requests[corev1.ResourceMemory]
-			podCPU += cpu.MilliValue()
-			podMemory += memory.Value()
-		}
+		requests := podRequests(&pod)
+		podCPU := requests.Cpu().MilliValue()
+		podMemory := requests.Memory().Value()
+		podScalar := newScalarResources(requests)
+		podScalar[corev1.ResourcePods] = 1 // Every pod takes one of its node's pods
 
 		// Skip runner pods (they have this label from actions-runner-controller)
 		if isRunnerPod(pod) {
-			excludedCPUMillis += podCPU
-			excludedMemoryBytes += podMemory
-			excludedPods++
+			usage.excludedCPUMillis += podCPU
+			usage.excludedMemoryBytes += podMemory
+			usage.excludedCount++
 			continue
 		}
 
-		totalCPUMillis += podCPU
-		totalMemoryBytes += podMemory
-		countedPods++
+		// Pending pods the scheduler hasn't placed yet don't use any node's capacity
+		if pod.Spec.NodeName == "" {
+			usage.unscheduledCPUMillis += podCPU
+			usage.unscheduledMemoryBytes += podMemory
+			usage.unscheduledScalar.add(podScalar, 1)
+			usage.unscheduledCount++
+			continue
+		}
+
+		// Pods on nodes that aren't ready or are dropped by the node filter use capacity that isn't counted
+		if _, ok := capacityNodes[pod.Spec.NodeName]; !ok {
+			usage.ignoredCount++
+			continue
+		}
+
+		usage.cpuMillis += podCPU
+		usage.memoryBytes += podMemory
+		usage.scalar.add(podScalar, 1)
+		usage.podCount++
+
+		usage.nodeCPUMillis[pod.Spec.NodeName] += podCPU
+		usage.nodeMemoryBytes[pod.Spec.NodeName] += podMemory
+		if usage.nodeScalar[pod.Spec.NodeName] == nil {
+			usage.nodeScalar[pod.Spec.NodeName] = ScalarResources{}
+		}
+		usage.nodeScalar[pod.Spec.NodeName].add(podScalar, 1)
 	}
 
-	return totalCPUMillis, totalMemoryBytes, excludedCPUMillis, excludedMemoryBytes, countedPods, excludedPods, nil
+	return usage, nil
+}
+
+// podRequests returns the effective resource requests of a pod, calculated the same way the scheduler does:
+// the larger of the biggest init container and the app containers, plus restartable sidecar init containers
+// and the pod overhead. Pod-level requests take precedence over container requests, and resized requests
+// reported in the pod status are used when they are larger.
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	return resourcehelper.PodRequests(pod, resourcehelper.PodResourcesOptions{UseStatusResources: true})
 }
 
 // isRunnerPod checks if a pod is a GitHub Actions runner pod
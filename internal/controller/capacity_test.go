@@ -3,13 +3,19 @@ package controller
 import (
 	"context"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
 )
 
 func TestCapacityCalculator_Calculate(t *testing.T) {
@@ -121,9 +127,9 @@ func TestCapacityCalculator_Calculate(t *testing.T) {
 				WithRuntimeObjects(objs...).
 				Build()
 
-			calculator := NewCapacityCalculator(fakeClient, slog.Default(), tt.cpuBufferPercent, tt.memBufferPercent)
+			calculator := NewCapacityCalculator(fakeClient, slog.Default())
 
-			capacity, err := calculator.Calculate(context.Background())
+			capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{CPUPercent: tt.cpuBufferPercent, MemoryPercent: tt.memBufferPercent}, config.NodeFilterConfig{})
 			if err != nil {
 				t.Fatalf("Calculate() error = %v", err)
 			}
@@ -139,6 +145,82 @@ func TestCapacityCalculator_Calculate(t *testing.T) {
 	}
 }
 
+func TestCapacityCalculator_CalculateNodes(t *testing.T) {
+	nodes := []corev1.Node{
+		makeNode("node1", "4000m", "16Gi", corev1.ConditionTrue),
+		makeNode("node2", "4000m", "16Gi", corev1.ConditionTrue),
+		makeNode("node3", "4000m", "16Gi", corev1.ConditionFalse), // Not ready
+	}
+	pods := []corev1.Pod{
+		makePod("pod1", "node1", "2500m", "4Gi", corev1.PodRunning),
+		makePod("pod2", "node2", "5000m", "2Gi", corev1.PodRunning), // Over-committed CPU
+		makePod("pending", "", "1000m", "1Gi", corev1.PodPending),   // Not bound to a node
+		makePodWithLabels("runner1", "node1", "1000m", "2Gi", corev1.PodRunning, map[string]string{
+			"actions.github.com/scale-set-name": "my-runner-set",
+		}),
+	}
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	objs := make([]runtime.Object, 0)
+	for i := range nodes {
+		objs = append(objs, &nodes[i])
+	}
+	for i := range pods {
+		objs = append(objs, &pods[i])
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRuntimeObjects(objs...).
+		Build()
+
+	calculator := NewCapacityCalculator(fakeClient, slog.Default())
+
+	capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{CPUPercent: 10, MemoryPercent: 10}, config.NodeFilterConfig{})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	want := map[string]NodeCapacity{
+		"node1": {
+			Name:                   "node1",
+			AllocatableCPUMillis:   4000,
+			AllocatableMemoryBytes: 16 * 1024 * 1024 * 1024,
+			UsedCPUMillis:          2500, // Runner pod excluded
+			UsedMemoryBytes:        4 * 1024 * 1024 * 1024,
+			AvailableCPUMillis:     1350,        // (4000 - 2500) * 0.9
+			AvailableMemoryBytes:   11596411699, // (16Gi - 4Gi) * 0.9
+		},
+		"node2": {
+			Name:                   "node2",
+			AllocatableCPUMillis:   4000,
+			AllocatableMemoryBytes: 16 * 1024 * 1024 * 1024,
+			UsedCPUMillis:          5000,
+			UsedMemoryBytes:        2 * 1024 * 1024 * 1024,
+			AvailableCPUMillis:     0,           // Negative becomes 0
+			AvailableMemoryBytes:   13529146982, // (16Gi - 2Gi) * 0.9
+		},
+	}
+
+	if len(capacity.Nodes) != len(want) {
+		t.Fatalf("len(Nodes) = %v, want %v", len(capacity.Nodes), len(want))
+	}
+	for _, node := range capacity.Nodes {
+		wantNode, ok := want[node.Name]
+		if !ok {
+			t.Errorf("unexpected node %s", node.Name)
+			continue
+		}
+		if node.AllocatableCPUMillis != wantNode.AllocatableCPUMillis || node.AllocatableMemoryBytes != wantNode.AllocatableMemoryBytes ||
+			node.UsedCPUMillis != wantNode.UsedCPUMillis || node.UsedMemoryBytes != wantNode.UsedMemoryBytes ||
+			node.AvailableCPUMillis != wantNode.AvailableCPUMillis || node.AvailableMemoryBytes != wantNode.AvailableMemoryBytes {
+			t.Errorf("node %s = %+v, want %+v", node.Name, node, wantNode)
+		}
+	}
+}
+
 func TestParseCPU(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -236,6 +318,7 @@ func makeNode(name, cpu, memory string, readyStatus corev1.ConditionStatus) core
 			Allocatable: corev1.ResourceList{
 				corev1.ResourceCPU:    resource.MustParse(cpu),
 				corev1.ResourceMemory: resource.MustParse(memory),
+				corev1.ResourcePods:   resource.MustParse("110"),
 			},
 			Conditions: []corev1.NodeCondition{
 				{
@@ -279,6 +362,90 @@ func makePodWithLabels(name, nodeName, cpu, memory string, phase corev1.PodPhase
 	return pod
 }
 
+func TestCapacityCalculator_CalculateWithReserves(t *testing.T) {
+	const gi = 1024 * 1024 * 1024
+
+	tests := []struct {
+		name                     string
+		buffers                  CapacityBuffers
+		wantAvailableCPUMillis   int64
+		wantAvailableMemoryBytes int64
+		wantReservedCPUMillis    int64
+		wantNodeCPUMillis        map[string]int64
+	}{
+		{
+			name:                     "no reserves",
+			buffers:                  CapacityBuffers{},
+			wantAvailableCPUMillis:   4500, // node1: 4000 - 3500, node2: 4000
+			wantAvailableMemoryBytes: 14 * gi,
+			wantReservedCPUMillis:    0,
+			wantNodeCPUMillis:        map[string]int64{"node1": 500, "node2": 4000},
+		},
+		{
+			name:                     "cluster reserve",
+			buffers:                  CapacityBuffers{CPUReserveMillis: 1000, MemoryReserveBytes: 4 * gi},
+			wantAvailableCPUMillis:   3500,
+			wantAvailableMemoryBytes: 10 * gi,
+			wantReservedCPUMillis:    1000,
+			wantNodeCPUMillis:        map[string]int64{"node1": 388, "node2": 3112}, // Reserve spread 112 / 888 by free CPU
+		},
+		{
+			name:                     "node reserve is limited to each node's free capacity",
+			buffers:                  CapacityBuffers{NodeCPUReserveMillis: 1000},
+			wantAvailableCPUMillis:   3000, // 4500 - 500 (node1) - 1000 (node2)
+			wantAvailableMemoryBytes: 14 * gi,
+			wantReservedCPUMillis:    1500,
+			wantNodeCPUMillis:        map[string]int64{"node1": 0, "node2": 3000},
+		},
+		{
+			name:                     "reserves before percentage buffer",
+			buffers:                  CapacityBuffers{CPUPercent: 10, CPUReserveMillis: 1000, NodeCPUReserveMillis: 1000},
+			wantAvailableCPUMillis:   1800, // (4500 - 1500 - 1000) * 0.9
+			wantAvailableMemoryBytes: 14 * gi,
+			wantReservedCPUMillis:    2700,
+			wantNodeCPUMillis:        map[string]int64{"node1": 0, "node2": 1800}, // (4000 - 1000 - 1000) * 0.9
+		},
+		{
+			name:                     "reserve above free capacity",
+			buffers:                  CapacityBuffers{CPUReserveMillis: 8000},
+			wantAvailableCPUMillis:   0,
+			wantAvailableMemoryBytes: 14 * gi,
+			wantReservedCPUMillis:    4500,
+			wantNodeCPUMillis:        map[string]int64{"node1": 0, "node2": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node1 := makeNode("node1", "4000m", "8Gi", corev1.ConditionTrue)
+			node2 := makeNode("node2", "4000m", "8Gi", corev1.ConditionTrue)
+			pod := makePod("pod1", "node1", "3500m", "2Gi", corev1.PodRunning)
+
+			calculator := NewCapacityCalculator(newFakeClient(t, &node1, &node2, &pod), slog.Default())
+
+			capacity, err := calculator.Calculate(context.Background(), tt.buffers, config.NodeFilterConfig{})
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+
+			if capacity.AvailableCPUMillis != tt.wantAvailableCPUMillis {
+				t.Errorf("AvailableCPUMillis = %v, want %v", capacity.AvailableCPUMillis, tt.wantAvailableCPUMillis)
+			}
+			if capacity.AvailableMemoryBytes != tt.wantAvailableMemoryBytes {
+				t.Errorf("AvailableMemoryBytes = %v, want %v", capacity.AvailableMemoryBytes, tt.wantAvailableMemoryBytes)
+			}
+			if capacity.ReservedCPUMillis != tt.wantReservedCPUMillis {
+				t.Errorf("ReservedCPUMillis = %v, want %v", capacity.ReservedCPUMillis, tt.wantReservedCPUMillis)
+			}
+			for _, node := range capacity.Nodes {
+				if node.AvailableCPUMillis != tt.wantNodeCPUMillis[node.Name] {
+					t.Errorf("%s AvailableCPUMillis = %v, want %v", node.Name, node.AvailableCPUMillis, tt.wantNodeCPUMillis[node.Name])
+				}
+			}
+		})
+	}
+}
+
 func TestCapacityCalculator_CalculateWithRunnerPods(t *testing.T) {
 	tests := []struct {
 		name                     string
@@ -288,6 +455,7 @@ func TestCapacityCalculator_CalculateWithRunnerPods(t *testing.T) {
 		memBufferPercent         int
 		wantAvailableCPUMillis   int64
 		wantAvailableMemoryBytes int64
+		wantExcludedCPUMillis    int64
 	}{
 		{
 			name: "runner pods should be excluded from usage",
@@ -304,6 +472,7 @@ func TestCapacityCalculator_CalculateWithRunnerPods(t *testing.T) {
 			memBufferPercent:         10,
 			wantAvailableCPUMillis:   7200,        // (10000 - 2000) * 0.9, runner excluded
 			wantAvailableMemoryBytes: 15461882265, // (20Gi - 4Gi) * 0.9, runner excluded
+			wantExcludedCPUMillis:    1000,
 		},
 		{
 			name: "runner pods with component label should be excluded",
@@ -320,6 +489,7 @@ func TestCapacityCalculator_CalculateWithRunnerPods(t *testing.T) {
 			memBufferPercent:         10,
 			wantAvailableCPUMillis:   7200,        // (10000 - 2000) * 0.9, runner excluded
 			wantAvailableMemoryBytes: 15461882265, // (20Gi - 4Gi) * 0.9, runner excluded
+			wantExcludedCPUMillis:    1000,
 		},
 		{
 			name: "only runner pods - all capacity available",
@@ -335,6 +505,7 @@ func TestCapacityCalculator_CalculateWithRunnerPods(t *testing.T) {
 			memBufferPercent:         10,
 			wantAvailableCPUMillis:   9000,        // 10000 * 0.9, all runners excluded
 			wantAvailableMemoryBytes: 19327352832, // 20Gi * 0.9, all runners excluded
+			wantExcludedCPUMillis:    2000,
 		},
 	}
 
@@ -357,9 +528,9 @@ func TestCapacityCalculator_CalculateWithRunnerPods(t *testing.T) {
 				WithRuntimeObjects(objs...).
 				Build()
 
-			calculator := NewCapacityCalculator(fakeClient, slog.Default(), tt.cpuBufferPercent, tt.memBufferPercent)
+			calculator := NewCapacityCalculator(fakeClient, slog.Default())
 
-			capacity, err := calculator.Calculate(context.Background())
+			capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{CPUPercent: tt.cpuBufferPercent, MemoryPercent: tt.memBufferPercent}, config.NodeFilterConfig{})
 			if err != nil {
 				t.Fatalf("Calculate() error = %v", err)
 			}
@@ -371,10 +542,438 @@ func TestCapacityCalculator_CalculateWithRunnerPods(t *testing.T) {
 			if capacity.AvailableMemoryBytes != tt.wantAvailableMemoryBytes {
 				t.Errorf("AvailableMemoryBytes = %v, want %v", capacity.AvailableMemoryBytes, tt.wantAvailableMemoryBytes)
 			}
+
+			if capacity.ExcludedCPUMillis != tt.wantExcludedCPUMillis {
+				t.Errorf("ExcludedCPUMillis = %v, want %v", capacity.ExcludedCPUMillis, tt.wantExcludedCPUMillis)
+			}
+		})
+	}
+}
+
+func TestCapacityCalculator_CalculateExtendedResources(t *testing.T) {
+	gpuNode := makeNode("gpu-1", "8000m", "32Gi", corev1.ConditionTrue)
+	gpuNode.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("4")
+	gpuNode.Status.Allocatable["hugepages-2Mi"] = resource.MustParse("1Gi")
+	cpuNode := makeNode("cpu-1", "8000m", "32Gi", corev1.ConditionTrue)
+
+	withGPUs := func(pod corev1.Pod, gpus string) *corev1.Pod {
+		pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse(gpus)
+		return &pod
+	}
+	training := withGPUs(makePod("training", "gpu-1", "1000m", "1Gi", corev1.PodRunning), "1")
+	pending := withGPUs(makePod("pending", "", "1000m", "1Gi", corev1.PodPending), "1")
+	runner := withGPUs(makePodWithLabels("runner", "gpu-1", "1000m", "1Gi", corev1.PodRunning, map[string]string{
+		"actions.github.com/scale-set-name": "ml",
+	}), "1")
+
+	calculator := NewCapacityCalculator(newFakeClient(t, &gpuNode, &cpuNode, training, pending, runner), slog.Default())
+	capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{CPUPercent: 10, MemoryPercent: 10}, config.NodeFilterConfig{})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	// Buffers don't apply to extended resources, runner pods are excluded like for CPU and memory,
+	// and the pending pod isn't bound to a node yet
+	wantTotal := ScalarResources{"nvidia.com/gpu": 4, "hugepages-2Mi": 1024 * 1024 * 1024, corev1.ResourcePods: 220}
+	wantAvailable := ScalarResources{"nvidia.com/gpu": 3, "hugepages-2Mi": 1024 * 1024 * 1024, corev1.ResourcePods: 219}
+	if !maps.Equal(capacity.TotalScalar, wantTotal) {
+		t.Errorf("TotalScalar = %v, want %v", capacity.TotalScalar, wantTotal)
+	}
+	if !maps.Equal(capacity.AvailableScalar, wantAvailable) {
+		t.Errorf("AvailableScalar = %v, want %v", capacity.AvailableScalar, wantAvailable)
+	}
+
+	for _, node := range capacity.Nodes {
+		want := ScalarResources{corev1.ResourcePods: 110}
+		if node.Name == "gpu-1" {
+			want = ScalarResources{"nvidia.com/gpu": 3, "hugepages-2Mi": 1024 * 1024 * 1024, corev1.ResourcePods: 109}
+		}
+		if !maps.Equal(node.AvailableScalar, want) {
+			t.Errorf("node %s AvailableScalar = %v, want %v", node.Name, node.AvailableScalar, want)
+		}
+	}
+}
+
+func TestCapacityCalculator_CalculateEphemeralStorage(t *testing.T) {
+	node := makeNode("node1", "8000m", "32Gi", corev1.ConditionTrue)
+	node.Status.Allocatable[corev1.ResourceEphemeralStorage] = resource.MustParse("100Gi")
+	build := makePod("build", "node1", "1000m", "1Gi", corev1.PodRunning)
+	build.Spec.Containers[0].Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("30Gi")
+	runner := makePodWithLabels("runner", "node1", "1000m", "1Gi", corev1.PodRunning, map[string]string{
+		"actions.github.com/scale-set-name": "build",
+	})
+	runner.Spec.Containers[0].Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("20Gi")
+
+	calculator := NewCapacityCalculator(newFakeClient(t, &node, &build, &runner), slog.Default())
+	capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{CPUPercent: 10, MemoryPercent: 10}, config.NodeFilterConfig{})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	const gi = 1024 * 1024 * 1024
+	if got := capacity.TotalScalar[corev1.ResourceEphemeralStorage]; got != 100*gi {
+		t.Errorf("total ephemeral storage = %v, want 100Gi", got)
+	}
+	if got := capacity.UsedScalar[corev1.ResourceEphemeralStorage]; got != 30*gi {
+		t.Errorf("used ephemeral storage = %v, want 30Gi (runner pod excluded)", got)
+	}
+	if got := capacity.AvailableScalar[corev1.ResourceEphemeralStorage]; got != 70*gi {
+		t.Errorf("available ephemeral storage = %v, want 70Gi", got)
+	}
+	if got := capacity.Nodes[0].AvailableScalar[corev1.ResourceEphemeralStorage]; got != 70*gi {
+		t.Errorf("node available ephemeral storage = %v, want 70Gi", got)
+	}
+}
+
+func TestCapacityCalculator_CalculateNodeFilter(t *testing.T) {
+	withLabels := func(node corev1.Node, labels map[string]string) corev1.Node {
+		node.Labels = labels
+		return node
+	}
+	withTaint := func(node corev1.Node, key string, effect corev1.TaintEffect) corev1.Node {
+		node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{Key: key, Effect: effect})
+		return node
+	}
+	cordoned := makeNode("cordoned", "8000m", "32Gi", corev1.ConditionTrue)
+	cordoned.Spec.Unschedulable = true
+	cordoned = withTaint(cordoned, corev1.TaintNodeUnschedulable, corev1.TaintEffectNoSchedule)
+
+	nodes := []corev1.Node{
+		withLabels(makeNode("ci", "8000m", "32Gi", corev1.ConditionTrue), map[string]string{"node-pool": "ci"}),
+		withLabels(makeNode("system", "8000m", "32Gi", corev1.ConditionTrue), map[string]string{"node-pool": "system"}),
+		makeNode("not-ready", "8000m", "32Gi", corev1.ConditionFalse),
+		cordoned,
+		withTaint(makeNode("draining", "8000m", "32Gi", corev1.ConditionTrue), toBeDeletedTaint, corev1.TaintEffectNoSchedule),
+		withTaint(makeNode("dedicated", "8000m", "32Gi", corev1.ConditionTrue), "dedicated", corev1.TaintEffectNoSchedule),
+	}
+
+	tests := []struct {
+		name        string
+		filter      config.NodeFilterConfig
+		wantNodes   []string
+		wantDropped map[string]string
+		wantErr     string
+	}{
+		{
+			name:      "only not ready nodes dropped without filter",
+			wantNodes: []string{"ci", "cordoned", "dedicated", "draining", "system"},
+			wantDropped: map[string]string{
+				"not-ready": nodeDropReasonNotReady,
+			},
+		},
+		{
+			name:      "default filter",
+			filter:    config.DefaultConfig().NodeFilter,
+			wantNodes: []string{"ci", "dedicated", "system"},
+			wantDropped: map[string]string{
+				"not-ready": nodeDropReasonNotReady,
+				"cordoned":  nodeDropReasonCordoned,
+				"draining":  nodeDropReasonToBeDeleted,
+			},
+		},
+		{
+			name:      "tainted nodes",
+			filter:    config.NodeFilterConfig{ExcludeTainted: true},
+			wantNodes: []string{"ci", "system"},
+			wantDropped: map[string]string{
+				"not-ready": nodeDropReasonNotReady,
+				"cordoned":  nodeDropReasonTainted,
+				"draining":  nodeDropReasonTainted,
+				"dedicated": nodeDropReasonTainted,
+			},
+		},
+		{
+			name: "label selectors",
+			filter: config.NodeFilterConfig{
+				LabelSelector:        "node-pool",
+				ExcludeLabelSelector: "node-pool=system",
+			},
+			wantNodes: []string{"ci"},
+			wantDropped: map[string]string{
+				"not-ready": nodeDropReasonNotReady,
+				"cordoned":  nodeDropReasonNotSelected,
+				"draining":  nodeDropReasonNotSelected,
+				"dedicated": nodeDropReasonNotSelected,
+				"system":    nodeDropReasonExcluded,
+			},
+		},
+		{
+			name:    "invalid label selector",
+			filter:  config.NodeFilterConfig{LabelSelector: "node-pool in ci"},
+			wantErr: "invalid node label selector",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := make([]client.Object, 0, len(nodes))
+			for i := range nodes {
+				objs = append(objs, &nodes[i])
+			}
+			calculator := NewCapacityCalculator(newFakeClient(t, objs...), slog.Default())
+
+			capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{CPUPercent: 10, MemoryPercent: 10}, tt.filter)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Calculate() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+
+			gotNodes := make([]string, 0, len(capacity.Nodes))
+			for _, node := range capacity.Nodes {
+				gotNodes = append(gotNodes, node.Name)
+			}
+			slices.Sort(gotNodes)
+			if !slices.Equal(gotNodes, tt.wantNodes) {
+				t.Errorf("Nodes = %v, want %v", gotNodes, tt.wantNodes)
+			}
+			if !maps.Equal(capacity.DroppedNodes, tt.wantDropped) {
+				t.Errorf("DroppedNodes = %v, want %v", capacity.DroppedNodes, tt.wantDropped)
+			}
+			if want := int64(len(tt.wantNodes)) * 8000; capacity.TotalCPUMillis != want {
+				t.Errorf("TotalCPUMillis = %d, want %d", capacity.TotalCPUMillis, want)
+			}
+		})
+	}
+}
+
+func TestCapacityCalculator_CalculateUnscheduledPods(t *testing.T) {
+	nodes := []corev1.Node{
+		makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue),
+		makeNode("node2", "10000m", "20Gi", corev1.ConditionFalse), // Not ready
+	}
+	pods := []corev1.Pod{
+		makePod("bound", "node1", "2000m", "4Gi", corev1.PodRunning),
+		makePod("not-ready-node", "node2", "3000m", "6Gi", corev1.PodRunning), // Not counted toward capacity
+		makePod("pending", "", "1000m", "2Gi", corev1.PodPending),             // Not bound to a node
+	}
+
+	tests := []struct {
+		name                     string
+		reserveUnscheduled       bool
+		wantAvailableCPUMillis   int64
+		wantReservedCPUMillis    int64
+		wantAvailablePods        int64
+		wantAvailableMemoryBytes int64
+	}{
+		{
+			name:                     "unscheduled pods not reserved",
+			wantAvailableCPUMillis:   7200, // (10000 - 2000) * 0.9
+			wantReservedCPUMillis:    800,
+			wantAvailablePods:        109,
+			wantAvailableMemoryBytes: 15461882265, // (20Gi - 4Gi) * 0.9
+		},
+		{
+			name:                     "unscheduled pods reserved",
+			reserveUnscheduled:       true,
+			wantAvailableCPUMillis:   6300, // (10000 - 2000 - 1000) * 0.9
+			wantReservedCPUMillis:    1700,
+			wantAvailablePods:        108,
+			wantAvailableMemoryBytes: 13529146982, // (20Gi - 4Gi - 2Gi) * 0.9
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := make([]client.Object, 0, len(nodes)+len(pods))
+			for i := range nodes {
+				objs = append(objs, &nodes[i])
+			}
+			for i := range pods {
+				objs = append(objs, &pods[i])
+			}
+			calculator := NewCapacityCalculator(newFakeClient(t, objs...), slog.Default())
+
+			capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{CPUPercent: 10, MemoryPercent: 10, ReserveUnscheduled: tt.reserveUnscheduled}, config.NodeFilterConfig{})
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+
+			// Used and total come from the same nodes
+			if capacity.TotalCPUMillis != 10000 || capacity.UsedCPUMillis != 2000 {
+				t.Errorf("total/used CPU = %d/%d, want 10000/2000", capacity.TotalCPUMillis, capacity.UsedCPUMillis)
+			}
+			if capacity.UnscheduledCPUMillis != 1000 || capacity.UnscheduledMemoryBytes != 2*1024*1024*1024 {
+				t.Errorf("unscheduled = %dm/%d, want 1000m/2Gi", capacity.UnscheduledCPUMillis, capacity.UnscheduledMemoryBytes)
+			}
+			if got := capacity.UnscheduledScalar[corev1.ResourcePods]; got != 1 {
+				t.Errorf("unscheduled pods = %d, want 1", got)
+			}
+			if capacity.AvailableCPUMillis != tt.wantAvailableCPUMillis {
+				t.Errorf("AvailableCPUMillis = %d, want %d", capacity.AvailableCPUMillis, tt.wantAvailableCPUMillis)
+			}
+			if capacity.ReservedCPUMillis != tt.wantReservedCPUMillis {
+				t.Errorf("ReservedCPUMillis = %d, want %d", capacity.ReservedCPUMillis, tt.wantReservedCPUMillis)
+			}
+			if capacity.AvailableMemoryBytes != tt.wantAvailableMemoryBytes {
+				t.Errorf("AvailableMemoryBytes = %d, want %d", capacity.AvailableMemoryBytes, tt.wantAvailableMemoryBytes)
+			}
+			if got := capacity.AvailableScalar[corev1.ResourcePods]; got != tt.wantAvailablePods {
+				t.Errorf("available pods = %d, want %d", got, tt.wantAvailablePods)
+			}
+
+			// Reserved unscheduled pods are kept free on the nodes as well, here all on the only ready node
+			if capacity.Nodes[0].AvailableCPUMillis != tt.wantAvailableCPUMillis {
+				t.Errorf("node1 AvailableCPUMillis = %d, want %d", capacity.Nodes[0].AvailableCPUMillis, tt.wantAvailableCPUMillis)
+			}
+			if got := capacity.Nodes[0].AvailableScalar[corev1.ResourcePods]; got != tt.wantAvailablePods {
+				t.Errorf("node1 available pods = %d, want %d", got, tt.wantAvailablePods)
+			}
 		})
 	}
 }
 
+func TestCapacityCalculator_CalculatePods(t *testing.T) {
+	fullNode := makeNode("full", "8000m", "32Gi", corev1.ConditionTrue)
+	fullNode.Status.Allocatable[corev1.ResourcePods] = resource.MustParse("2")
+	emptyNode := makeNode("empty", "8000m", "32Gi", corev1.ConditionTrue)
+	daemon := makePod("daemon", "full", "100m", "128Mi", corev1.PodRunning)
+	app := makePod("app", "full", "100m", "128Mi", corev1.PodRunning)
+	done := makePod("done", "empty", "100m", "128Mi", corev1.PodSucceeded)
+	runner := makePodWithLabels("runner", "empty", "1000m", "1Gi", corev1.PodRunning, map[string]string{
+		"actions.github.com/scale-set-name": "build",
+	})
+
+	calculator := NewCapacityCalculator(newFakeClient(t, &fullNode, &emptyNode, &daemon, &app, &done, &runner), slog.Default())
+	capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{CPUPercent: 10, MemoryPercent: 10}, config.NodeFilterConfig{})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	// Terminated pods don't take a pod, runner pods are excluded like for CPU and memory
+	if got := capacity.TotalScalar[corev1.ResourcePods]; got != 112 {
+		t.Errorf("total pods = %d, want 112", got)
+	}
+	if got := capacity.UsedScalar[corev1.ResourcePods]; got != 2 {
+		t.Errorf("used pods = %d, want 2", got)
+	}
+	if got := capacity.AvailableScalar[corev1.ResourcePods]; got != 110 {
+		t.Errorf("available pods = %d, want 110", got)
+	}
+	for _, node := range capacity.Nodes {
+		want := int64(110)
+		if node.Name == "full" {
+			want = 0
+		}
+		if got := node.AvailableScalar[corev1.ResourcePods]; got != want {
+			t.Errorf("node %s available pods = %d, want %d", node.Name, got, want)
+		}
+	}
+}
+
+func TestPodRequests(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	container := func(name, cpu, memory string) corev1.Container {
+		return corev1.Container{
+			Name: name,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				},
+			},
+		}
+	}
+	sidecar := func(name, cpu, memory string) corev1.Container {
+		c := container(name, cpu, memory)
+		c.RestartPolicy = &always
+		return c
+	}
+
+	tests := []struct {
+		name            string
+		spec            corev1.PodSpec
+		wantCPUMillis   int64
+		wantMemoryBytes int64
+	}{
+		{
+			name: "app containers are summed",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{container("a", "500m", "1Gi"), container("b", "250m", "512Mi")},
+			},
+			wantCPUMillis:   750,
+			wantMemoryBytes: 1536 * 1024 * 1024,
+		},
+		{
+			name: "larger init container wins",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{container("init", "2000m", "256Mi")},
+				Containers:     []corev1.Container{container("app", "500m", "1Gi")},
+			},
+			wantCPUMillis:   2000,               // init container needs more CPU than the app
+			wantMemoryBytes: 1024 * 1024 * 1024, // app needs more memory than the init container
+		},
+		{
+			name: "sidecars are added to app containers",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{sidecar("proxy", "100m", "128Mi")},
+				Containers:     []corev1.Container{container("app", "500m", "1Gi")},
+			},
+			wantCPUMillis:   600,
+			wantMemoryBytes: 1152 * 1024 * 1024,
+		},
+		{
+			name: "overhead is added",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{container("app", "500m", "1Gi")},
+				Overhead: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("250m"),
+					corev1.ResourceMemory: resource.MustParse("120Mi"),
+				},
+			},
+			wantCPUMillis:   750,
+			wantMemoryBytes: 1144 * 1024 * 1024,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := podRequests(&corev1.Pod{Spec: tt.spec})
+			if got := requests.Cpu().MilliValue(); got != tt.wantCPUMillis {
+				t.Errorf("CPU = %v, want %v", got, tt.wantCPUMillis)
+			}
+			if got := requests.Memory().Value(); got != tt.wantMemoryBytes {
+				t.Errorf("memory = %v, want %v", got, tt.wantMemoryBytes)
+			}
+		})
+	}
+}
+
+func TestCapacityCalculator_CalculateWithInitContainersAndOverhead(t *testing.T) {
+	pod := makePod("pod1", "node1", "1000m", "2Gi", corev1.PodRunning)
+	pod.Spec.InitContainers = []corev1.Container{{
+		Name: "migrate",
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("3000m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	}}
+	pod.Spec.Overhead = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	calculator := NewCapacityCalculator(newFakeClient(t, &node, &pod), slog.Default())
+
+	capacity, err := calculator.Calculate(context.Background(), CapacityBuffers{}, config.NodeFilterConfig{})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+	if capacity.UsedCPUMillis != 3500 {
+		t.Errorf("UsedCPUMillis = %v, want 3500 (init container plus overhead)", capacity.UsedCPUMillis)
+	}
+	if capacity.UsedMemoryBytes != 3*1024*1024*1024 {
+		t.Errorf("UsedMemoryBytes = %v, want 3Gi (app container plus overhead)", capacity.UsedMemoryBytes)
+	}
+}
+
 func TestIsRunnerPod(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// demandHeadroom is the number of runners a busy runner set may start on top of its current demand.
+// It lets a new job start right away. Once the headroom is used, the runner set is saturated and
+// its limit is lifted in the next reconciliation.
+const demandHeadroom = 1
+
+// activeRunners returns the number of runners a runner set currently needs: the largest of the runners
+// it manages, the ephemeral runners pending or running, and the runners requested for assigned jobs
+func (r *RunnerSetResources) activeRunners() int {
+	return max(r.CurrentRunners, r.PendingRunners+r.RunningRunners, r.DesiredRunners)
+}
+
+// demandLimit returns the maxRunners a runner set is limited to by its demand, or nil if it isn't limited.
+//
+//   - Idle runner sets keep their minimum runners, but at least the headroom. An idle runner set
+//     limited to 0 runners could never pick up a job and show demand again.
+//   - Saturated runner sets, which use all runners their current maxRunners allows, aren't limited.
+//     The listener never requests more runners than maxRunners, so their actual demand is unknown.
+//   - Other runner sets are limited to their active runners plus headroom.
+func demandLimit(rs *RunnerSetResources) *int {
+	active := rs.activeRunners()
+
+	var limit int
+	switch {
+	case active == 0:
+		limit = max(rs.MinRunners, demandHeadroom)
+	case active >= rs.CurrentMax:
+		return nil
+	default:
+		limit = max(active+demandHeadroom, rs.MinRunners)
+	}
+	return &limit
+}
+
+// desiredRunnersByOwner maps runner sets to the replicas the listener requested from their EphemeralRunnerSets.
+// ARC's listener doesn't publish assigned jobs in its status, but scales the EphemeralRunnerSet to them.
+func desiredRunnersByOwner(ephemeralRunnerSets []actionsv1alpha1.EphemeralRunnerSet) map[types.UID]int {
+	desired := make(map[types.UID]int, len(ephemeralRunnerSets))
+	for i := range ephemeralRunnerSets {
+		owner := metav1.GetControllerOf(&ephemeralRunnerSets[i])
+		if owner == nil || owner.Kind != "AutoscalingRunnerSet" {
+			continue
+		}
+		desired[owner.UID] += ephemeralRunnerSets[i].Spec.Replicas
+	}
+	return desired
+}
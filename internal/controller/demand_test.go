@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDemandLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		rs   *RunnerSetResources
+		want *int // nil means no limit
+	}{
+		{
+			name: "idle runner set keeps headroom for a new job",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 10},
+			want: intPtr(1),
+		},
+		{
+			name: "idle runner set keeps its minimum runners",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 10, MinRunners: 2},
+			want: intPtr(2),
+		},
+		{
+			name: "idle runner set scaled to zero gets headroom again",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 0},
+			want: intPtr(1),
+		},
+		{
+			name: "busy runner set is limited to active runners plus headroom",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 10, CurrentRunners: 3},
+			want: intPtr(4),
+		},
+		{
+			name: "pending and running ephemeral runners count as active",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 10, CurrentRunners: 2, PendingRunners: 2, RunningRunners: 3},
+			want: intPtr(6),
+		},
+		{
+			name: "runners requested for assigned jobs count as active",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 10, CurrentRunners: 2, DesiredRunners: 7},
+			want: intPtr(8),
+		},
+		{
+			name: "minimum runners above demand are kept",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 10, CurrentRunners: 1, MinRunners: 5},
+			want: intPtr(5),
+		},
+		{
+			name: "saturated runner set is not limited",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 4, CurrentRunners: 4},
+			want: nil,
+		},
+		{
+			name: "listener requesting all allowed runners is saturated",
+			rs:   &RunnerSetResources{Name: "test", CurrentMax: 4, CurrentRunners: 2, DesiredRunners: 4},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := demandLimit(tt.rs)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("demandLimit() = %v, want %v", formatLimit(got), formatLimit(tt.want))
+			}
+		})
+	}
+}
+
+func TestDesiredRunnersByOwner(t *testing.T) {
+	controller := true
+	ephemeralRunnerSet := func(name string, replicas int, ownerKind string, ownerUID types.UID, isController bool) actionsv1alpha1.EphemeralRunnerSet {
+		return actionsv1alpha1.EphemeralRunnerSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: ownerKind, Name: "owner", UID: ownerUID, Controller: &isController},
+				},
+			},
+			Spec: actionsv1alpha1.EphemeralRunnerSetSpec{Replicas: replicas},
+		}
+	}
+
+	got := desiredRunnersByOwner([]actionsv1alpha1.EphemeralRunnerSet{
+		ephemeralRunnerSet("a-1", 3, "AutoscalingRunnerSet", "uid-a", controller),
+		ephemeralRunnerSet("a-2", 1, "AutoscalingRunnerSet", "uid-a", controller), // Replaced during a template change
+		ephemeralRunnerSet("b-1", 5, "AutoscalingRunnerSet", "uid-b", controller),
+		ephemeralRunnerSet("other", 7, "Deployment", "uid-c", controller),
+		ephemeralRunnerSet("not-controlled", 2, "AutoscalingRunnerSet", "uid-d", false),
+	})
+
+	want := map[types.UID]int{"uid-a": 4, "uid-b": 5}
+	if len(got) != len(want) {
+		t.Errorf("desiredRunnersByOwner() = %v, want %v", got, want)
+	}
+	for uid, wantReplicas := range want {
+		if got[uid] != wantReplicas {
+			t.Errorf("desired runners for %s = %d, want %d", uid, got[uid], wantReplicas)
+		}
+	}
+}
+
+// Helper functions
+
+func formatLimit(limit *int) any {
+	if limit == nil {
+		return "no limit"
+	}
+	return *limit
+}
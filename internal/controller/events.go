@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+// Reasons of the Events recorded on AutoscalingRunnerSets
+const (
+	eventReasonScaledUp          = "ScaledUp"
+	eventReasonScaledDown        = "ScaledDown"
+	eventReasonSafetyCapApplied  = "SafetyCapApplied"
+	eventReasonInvalidAnnotation = "InvalidAnnotation"
+)
+
+// Factors limiting the maxRunners of a runner set, used in Event messages
+const (
+	limitingFactorDemand        = "demand"
+	limitingFactorConfiguredMax = "configured max"
+	limitingFactorCPU           = "cpu"
+	limitingFactorMemory        = "memory"
+	limitingFactorRunningJobs   = "running jobs"
+)
+
+// recordEvent records an Event on a runner set, so `kubectl describe` shows why its maxRunners changed.
+// Nothing is recorded in dry-run mode or before an event recorder is set up (see SetupWithManager).
+func (r *Reconciler) recordEvent(cfg *config.Config, object runtime.Object, eventType, reason, messageFmt string, args ...any) {
+	if r.recorder == nil || cfg.DryRun {
+		return
+	}
+	r.recorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// limitingFactor describes what limits the maxRunners allocated to a runner set: its demand, its configured max,
+// or otherwise the resource it needs the largest share of the available capacity of. The pod count, ephemeral storage
+// and extended resources are named by their resource name, e.g. "pods", "ephemeral-storage" or "nvidia.com/gpu".
+func limitingFactor(rs *RunnerSetResources, maxRunners int, capacity *ClusterCapacity) string {
+	if limit := rs.maxRunnersCap(); limit >= 0 && maxRunners >= limit {
+		if rs.DemandMax != nil && limit == max(*rs.DemandMax, 0) {
+			return limitingFactorDemand
+		}
+		return limitingFactorConfiguredMax
+	}
+
+	factor, share := limitingFactorCPU, float64(rs.CPUMillis)/float64(max(capacity.AvailableCPUMillis, 1))
+	if memoryShare := float64(rs.MemoryBytes) / float64(max(capacity.AvailableMemoryBytes, 1)); memoryShare > share {
+		factor, share = limitingFactorMemory, memoryShare
+	}
+	for _, name := range rs.ScalarResources.names() {
+		if scalarShare := float64(rs.ScalarResources[name]) / float64(max(capacity.AvailableScalar[name], 1)); scalarShare > share {
+			factor, share = string(name), scalarShare
+		}
+	}
+	return factor
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestLimitingFactor(t *testing.T) {
+	capacity := &ClusterCapacity{
+		AvailableCPUMillis:   8000,
+		AvailableMemoryBytes: 16 * 1024 * 1024 * 1024,
+		AvailableScalar:      ScalarResources{"nvidia.com/gpu": 2, corev1.ResourceEphemeralStorage: 100 * 1024 * 1024 * 1024},
+	}
+
+	tests := []struct {
+		name       string
+		rs         *RunnerSetResources
+		maxRunners int
+		want       string
+	}{
+		{
+			name:       "cpu bound runner set",
+			rs:         &RunnerSetResources{CPUMillis: 2000, MemoryBytes: 1 * 1024 * 1024 * 1024},
+			maxRunners: 4,
+			want:       limitingFactorCPU,
+		},
+		{
+			name:       "memory bound runner set",
+			rs:         &RunnerSetResources{CPUMillis: 500, MemoryBytes: 8 * 1024 * 1024 * 1024},
+			maxRunners: 2,
+			want:       limitingFactorMemory,
+		},
+		{
+			name:       "extended resource bound runner set",
+			rs:         &RunnerSetResources{CPUMillis: 2000, MemoryBytes: 1 * 1024 * 1024 * 1024, ScalarResources: ScalarResources{"nvidia.com/gpu": 1}},
+			maxRunners: 2,
+			want:       "nvidia.com/gpu",
+		},
+		{
+			name:       "ephemeral storage bound runner set",
+			rs:         &RunnerSetResources{CPUMillis: 500, MemoryBytes: 1 * 1024 * 1024 * 1024, ScalarResources: ScalarResources{corev1.ResourceEphemeralStorage: 50 * 1024 * 1024 * 1024}},
+			maxRunners: 2,
+			want:       "ephemeral-storage",
+		},
+		{
+			name:       "configured max reached",
+			rs:         &RunnerSetResources{CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, ConfiguredMax: 3},
+			maxRunners: 3,
+			want:       limitingFactorConfiguredMax,
+		},
+		{
+			name:       "demand limit reached",
+			rs:         &RunnerSetResources{CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, ConfiguredMax: 5, DemandMax: intPtr(2)},
+			maxRunners: 2,
+			want:       limitingFactorDemand,
+		},
+		{
+			name:       "demand limit above configured max",
+			rs:         &RunnerSetResources{CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, ConfiguredMax: 3, DemandMax: intPtr(4)},
+			maxRunners: 3,
+			want:       limitingFactorConfiguredMax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limitingFactor(tt.rs, tt.maxRunners, capacity); got != tt.want {
+				t.Errorf("limitingFactor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconciler_ReconcileOnce_RecordsEvents(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	busyPod := makePod("busy", "node1", "6000m", "4Gi", corev1.PodRunning)
+	annotations := func(priority string) map[string]string {
+		return map[string]string{
+			config.AnnotationEnabled:    "true",
+			config.AnnotationCPU:        "1000m",
+			config.AnnotationMemory:     "1Gi",
+			config.AnnotationPriority:   priority,
+			config.AnnotationMaxRunners: "10",
+		}
+	}
+
+	// Only 3 runners fit (3.6 CPUs), all go to the higher priority. The capped runner set still has 5 runners.
+	scaled := makeRunnerSet("default", "scaled", 10, annotations("2"))
+	capped := makeRunnerSet("default", "capped", 10, annotations("1"))
+	capped.Status.CurrentRunners = 5
+	invalid := makeRunnerSet("default", "invalid", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "lots",
+		config.AnnotationMemory:  "1Gi",
+	})
+	disabled := makeRunnerSet("default", "disabled", 10, nil)
+
+	cfg := config.DefaultConfig()
+	cfg.AllocationStrategy = config.AllocationStrategyStrictPriority
+	reconciler := NewReconciler(newFakeClient(t, &node, &busyPod, scaled, capped, invalid, disabled), testLogger(), cfg)
+	recorder := record.NewFakeRecorder(10)
+	reconciler.recorder = recorder
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	close(recorder.Events)
+
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+
+	wantEvents := []string{
+		"Warning InvalidAnnotation Autoscaling skipped: invalid CPU annotation",
+		"Warning SafetyCapApplied Computed maxRunners 0 is below the 5 current runners, keeping 5 to protect running jobs",
+		"Normal ScaledDown Scaled maxRunners from 10 to 5 (limited by running jobs)",
+		"Normal ScaledDown Scaled maxRunners from 10 to 3 (limited by cpu)",
+	}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("events = %q, want %d events", events, len(wantEvents))
+	}
+	for _, want := range wantEvents {
+		found := false
+		for _, event := range events {
+			if strings.HasPrefix(event, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("events = %q, missing %q", events, want)
+		}
+	}
+}
+
+func TestReconciler_ReconcileOnce_DryRunRecordsNoEvents(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.DryRun = true
+	reconciler := NewReconciler(newFakeClient(t, &node, runnerSet), testLogger(), cfg)
+	recorder := record.NewFakeRecorder(10)
+	reconciler.recorder = recorder
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("recorded %d events in dry-run mode, want 0", len(recorder.Events))
+	}
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Healthz is a liveness check failing when the reconciliation loop hasn't completed a reconciliation
+// within LivenessReconcileIntervals resync intervals, e.g. because a request to the API server hangs.
+// Kubernetes then restarts the controller.
+func (r *Reconciler) Healthz(_ *http.Request) error {
+	lastActive := r.lastActive.Load()
+	if lastActive == 0 {
+		// The loop hasn't started yet: caches are syncing or this replica is a standby
+		return nil
+	}
+
+	threshold := time.Duration(r.config.Load().LivenessReconcileIntervals) * r.config.Load().ReconcileInterval
+	if since := time.Since(time.Unix(0, lastActive)); since > threshold {
+		return fmt.Errorf("no reconciliation completed in %s (threshold %s)", since.Round(time.Second), threshold)
+	}
+	return nil
+}
+
+// Readyz is a readiness check passing once the first reconciliation succeeded.
+// Standby replicas waiting for leadership are ready, so they don't hold up rollouts.
+func (r *Reconciler) Readyz(_ *http.Request) error {
+	if r.isStandby() {
+		return nil
+	}
+	if !r.reconciled.Load() {
+		return errors.New("no successful reconciliation yet")
+	}
+	return nil
+}
+
+// isStandby reports whether this replica waits for leadership before reconciling
+func (r *Reconciler) isStandby() bool {
+	if !r.config.Load().LeaderElection.Enabled || !r.NeedLeaderElection() || r.elected == nil {
+		return false
+	}
+
+	select {
+	case <-r.elected:
+		return false
+	default:
+		return true
+	}
+}
+
+// markActive records that the reconciliation loop made progress
+func (r *Reconciler) markActive() {
+	r.lastActive.Store(time.Now().UnixNano())
+}
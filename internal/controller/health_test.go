@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestReconciler_Healthz(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ReconcileInterval = 30 * time.Second
+	cfg.LivenessReconcileIntervals = 3
+	reconciler := NewReconciler(newFakeClient(t), testLogger(), cfg)
+
+	// The loop hasn't started, e.g. while waiting for leadership
+	if err := reconciler.Healthz(nil); err != nil {
+		t.Errorf("Healthz() before start error = %v, want nil", err)
+	}
+
+	reconciler.lastActive.Store(time.Now().Add(-time.Minute).UnixNano())
+	if err := reconciler.Healthz(nil); err != nil {
+		t.Errorf("Healthz() within threshold error = %v, want nil", err)
+	}
+
+	// A wedged loop didn't complete a reconciliation for more than 3 intervals
+	reconciler.lastActive.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	if err := reconciler.Healthz(nil); err == nil {
+		t.Error("Healthz() beyond threshold error = nil, want error")
+	}
+
+	// Failed reconciliations still show the loop is alive
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+	failingCfg := config.DefaultConfig()
+	failingCfg.AllocationStrategy = "unknown"
+	failing := NewReconciler(newFakeClient(t, &node, runnerSet), testLogger(), failingCfg)
+	failing.lastActive.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	if err := failing.ReconcileOnce(context.Background()); err == nil {
+		t.Fatal("ReconcileOnce() error = nil, want error")
+	}
+	if err := failing.Healthz(nil); err != nil {
+		t.Errorf("Healthz() after failed reconciliation error = %v, want nil", err)
+	}
+}
+
+func TestReconciler_Readyz(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	k8sClient := newFakeClient(t, &node)
+
+	t.Run("ready after first successful reconciliation", func(t *testing.T) {
+		reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+		if err := reconciler.Readyz(nil); err == nil {
+			t.Error("Readyz() before reconciliation error = nil, want error")
+		}
+
+		if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+			t.Fatalf("ReconcileOnce() error = %v", err)
+		}
+		if err := reconciler.Readyz(nil); err != nil {
+			t.Errorf("Readyz() after reconciliation error = %v, want nil", err)
+		}
+	})
+
+	t.Run("standby replicas are ready", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.LeaderElection.Enabled = true
+		reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+		elected := make(chan struct{})
+		reconciler.elected = elected
+
+		if err := reconciler.Readyz(nil); err != nil {
+			t.Errorf("Readyz() of standby error = %v, want nil", err)
+		}
+
+		// Once elected, the leader is only ready after its first reconciliation
+		close(elected)
+		if err := reconciler.Readyz(nil); err == nil {
+			t.Error("Readyz() of leader before reconciliation error = nil, want error")
+		}
+	})
+
+	t.Run("dry-run replicas reconcile without leadership", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.LeaderElection.Enabled = true
+		cfg.DryRun = true
+		reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+		reconciler.elected = make(chan struct{})
+
+		if err := reconciler.Readyz(nil); err == nil {
+			t.Error("Readyz() of dry-run replica before reconciliation error = nil, want error")
+		}
+	})
+}
@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// metricsNamespace prefixes the names of all metrics exposed by the controller
+const metricsNamespace = "gha_runner_autoscaler"
+
+// Capacity types used as the "type" label of the capacity metrics
+const (
+	capacityTypeTotal       = "total"
+	capacityTypeUsed        = "used"
+	capacityTypeExcluded    = "excluded"
+	capacityTypeReserved    = "reserved"
+	capacityTypeUnscheduled = "unscheduled"
+	capacityTypeAvailable   = "available"
+)
+
+// Metrics contains the Prometheus metrics describing cluster capacity and allocation decisions
+type Metrics struct {
+	capacityCPUCores              *prometheus.GaugeVec
+	capacityMemoryBytes           *prometheus.GaugeVec
+	capacityEphemeralStorageBytes *prometheus.GaugeVec
+	capacityPods                  *prometheus.GaugeVec
+	capacityDroppedNodes          *prometheus.GaugeVec
+	capacityExtendedResource      *prometheus.GaugeVec
+
+	computedMaxRunners *prometheus.GaugeVec
+	appliedMaxRunners  *prometheus.GaugeVec
+	currentRunners     *prometheus.GaugeVec
+	safetyCaps         *prometheus.CounterVec
+	updateErrors       *prometheus.CounterVec
+
+	reconcileDuration prometheus.Histogram
+	reconcileErrors   prometheus.Counter
+	invalidRunnerSets prometheus.Gauge
+
+	// runnerSets contains the runner sets with exported per-runner set metrics,
+	// so metrics of runner sets that are deleted or disabled can be removed
+	runnerSets map[types.NamespacedName]struct{}
+}
+
+// NewMetrics creates the metrics. They are only exposed once registered.
+func NewMetrics() *Metrics {
+	runnerSetLabels := []string{"namespace", "name"}
+
+	return &Metrics{
+		capacityCPUCores: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "capacity_cpu_cores",
+			Help:      "CPU capacity of the cluster by type (total, used, excluded, reserved, unscheduled, available).",
+		}, []string{"type"}),
+		capacityMemoryBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "capacity_memory_bytes",
+			Help:      "Memory capacity of the cluster by type (total, used, excluded, reserved, unscheduled, available).",
+		}, []string{"type"}),
+		capacityEphemeralStorageBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "capacity_ephemeral_storage_bytes",
+			Help:      "Ephemeral storage capacity of the cluster by type (total, used, available).",
+		}, []string{"type"}),
+		capacityPods: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "capacity_pods",
+			Help:      "Pods the nodes of the cluster can run by type (total, used, unscheduled, available).",
+		}, []string{"type"}),
+		capacityDroppedNodes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "capacity_dropped_nodes",
+			Help:      "Nodes not counted toward capacity by reason (not_ready, cordoned, to_be_deleted, tainted, not_selected, excluded).",
+		}, []string{"reason"}),
+		capacityExtendedResource: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "capacity_extended_resource",
+			Help:      "Extended resource and hugepages capacity of the cluster by resource and type (total, used, available), in devices or bytes.",
+		}, []string{"resource", "type"}),
+		computedMaxRunners: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "runner_set_computed_max_runners",
+			Help:      "maxRunners calculated for the runner set by the allocation strategy.",
+		}, runnerSetLabels),
+		appliedMaxRunners: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "runner_set_applied_max_runners",
+			Help:      "maxRunners set on the runner set after the last reconciliation.",
+		}, runnerSetLabels),
+		currentRunners: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "runner_set_current_runners",
+			Help:      "Runners currently managed by the runner set.",
+		}, runnerSetLabels),
+		safetyCaps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "runner_set_safety_caps_total",
+			Help:      "Number of times the computed maxRunners was raised to the current runners to protect running jobs.",
+		}, runnerSetLabels),
+		updateErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "runner_set_update_errors_total",
+			Help:      "Number of failed updates of the runner set's maxRunners.",
+		}, runnerSetLabels),
+		reconcileDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of reconciliations.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		reconcileErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconcile_errors_total",
+			Help:      "Number of failed reconciliations.",
+		}),
+		invalidRunnerSets: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "invalid_runner_sets",
+			Help:      "Runner sets opted in to autoscaling, but skipped because of an invalid configuration.",
+		}),
+		runnerSets: map[types.NamespacedName]struct{}{},
+	}
+}
+
+// Register registers all metrics with the given registerer
+func (m *Metrics) Register(registerer prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		m.capacityCPUCores,
+		m.capacityMemoryBytes,
+		m.capacityEphemeralStorageBytes,
+		m.capacityPods,
+		m.capacityDroppedNodes,
+		m.capacityExtendedResource,
+		m.computedMaxRunners,
+		m.appliedMaxRunners,
+		m.currentRunners,
+		m.safetyCaps,
+		m.updateErrors,
+		m.reconcileDuration,
+		m.reconcileErrors,
+		m.invalidRunnerSets,
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordCapacity records the cluster capacity of a reconciliation
+func (m *Metrics) recordCapacity(capacity *ClusterCapacity) {
+	m.capacityCPUCores.WithLabelValues(capacityTypeTotal).Set(float64(capacity.TotalCPUMillis) / 1000)
+	m.capacityCPUCores.WithLabelValues(capacityTypeUsed).Set(float64(capacity.UsedCPUMillis) / 1000)
+	m.capacityCPUCores.WithLabelValues(capacityTypeExcluded).Set(float64(capacity.ExcludedCPUMillis) / 1000)
+	m.capacityCPUCores.WithLabelValues(capacityTypeReserved).Set(float64(capacity.ReservedCPUMillis) / 1000)
+	m.capacityCPUCores.WithLabelValues(capacityTypeUnscheduled).Set(float64(capacity.UnscheduledCPUMillis) / 1000)
+	m.capacityCPUCores.WithLabelValues(capacityTypeAvailable).Set(float64(capacity.AvailableCPUMillis) / 1000)
+
+	m.capacityMemoryBytes.WithLabelValues(capacityTypeTotal).Set(float64(capacity.TotalMemoryBytes))
+	m.capacityMemoryBytes.WithLabelValues(capacityTypeUsed).Set(float64(capacity.UsedMemoryBytes))
+	m.capacityMemoryBytes.WithLabelValues(capacityTypeExcluded).Set(float64(capacity.ExcludedMemoryBytes))
+	m.capacityMemoryBytes.WithLabelValues(capacityTypeReserved).Set(float64(capacity.ReservedMemoryBytes))
+	m.capacityMemoryBytes.WithLabelValues(capacityTypeUnscheduled).Set(float64(capacity.UnscheduledMemoryBytes))
+	m.capacityMemoryBytes.WithLabelValues(capacityTypeAvailable).Set(float64(capacity.AvailableMemoryBytes))
+
+	m.capacityEphemeralStorageBytes.WithLabelValues(capacityTypeTotal).Set(float64(capacity.TotalScalar[corev1.ResourceEphemeralStorage]))
+	m.capacityEphemeralStorageBytes.WithLabelValues(capacityTypeUsed).Set(float64(capacity.UsedScalar[corev1.ResourceEphemeralStorage]))
+	m.capacityEphemeralStorageBytes.WithLabelValues(capacityTypeAvailable).Set(float64(capacity.AvailableScalar[corev1.ResourceEphemeralStorage]))
+
+	m.capacityPods.WithLabelValues(capacityTypeTotal).Set(float64(capacity.TotalScalar[corev1.ResourcePods]))
+	m.capacityPods.WithLabelValues(capacityTypeUsed).Set(float64(capacity.UsedScalar[corev1.ResourcePods]))
+	m.capacityPods.WithLabelValues(capacityTypeUnscheduled).Set(float64(capacity.UnscheduledScalar[corev1.ResourcePods]))
+	m.capacityPods.WithLabelValues(capacityTypeAvailable).Set(float64(capacity.AvailableScalar[corev1.ResourcePods]))
+
+	droppedNodes := make(map[string]int, len(nodeDropReasons))
+	for _, reason := range capacity.DroppedNodes {
+		droppedNodes[reason]++
+	}
+	for _, reason := range nodeDropReasons {
+		m.capacityDroppedNodes.WithLabelValues(reason).Set(float64(droppedNodes[reason]))
+	}
+
+	// Reset, so resources no longer offered by any node are removed
+	m.capacityExtendedResource.Reset()
+	for name, total := range capacity.TotalScalar {
+		if !isExtendedResource(name) {
+			continue
+		}
+		m.capacityExtendedResource.WithLabelValues(string(name), capacityTypeTotal).Set(float64(total))
+		m.capacityExtendedResource.WithLabelValues(string(name), capacityTypeUsed).Set(float64(capacity.UsedScalar[name]))
+		m.capacityExtendedResource.WithLabelValues(string(name), capacityTypeAvailable).Set(float64(capacity.AvailableScalar[name]))
+	}
+}
+
+// recordRunnerSet records the allocation decision for a runner set
+func (m *Metrics) recordRunnerSet(key types.NamespacedName, computedMax, appliedMax, currentRunners int) {
+	m.computedMaxRunners.WithLabelValues(key.Namespace, key.Name).Set(float64(computedMax))
+	m.appliedMaxRunners.WithLabelValues(key.Namespace, key.Name).Set(float64(appliedMax))
+	m.currentRunners.WithLabelValues(key.Namespace, key.Name).Set(float64(currentRunners))
+	m.runnerSets[key] = struct{}{}
+}
+
+// recordSafetyCap records that a runner set's maxRunners was raised to its current runners
+func (m *Metrics) recordSafetyCap(key types.NamespacedName) {
+	m.safetyCaps.WithLabelValues(key.Namespace, key.Name).Inc()
+}
+
+// recordUpdateError records a failed update of a runner set
+func (m *Metrics) recordUpdateError(key types.NamespacedName) {
+	m.updateErrors.WithLabelValues(key.Namespace, key.Name).Inc()
+}
+
+// forgetRunnerSets removes the per-runner set metrics of all runner sets not in keep
+func (m *Metrics) forgetRunnerSets(keep map[types.NamespacedName]struct{}) {
+	for key := range m.runnerSets {
+		if _, ok := keep[key]; ok {
+			continue
+		}
+		for _, vec := range []*prometheus.MetricVec{
+			m.computedMaxRunners.MetricVec,
+			m.appliedMaxRunners.MetricVec,
+			m.currentRunners.MetricVec,
+			m.safetyCaps.MetricVec,
+			m.updateErrors.MetricVec,
+		} {
+			vec.DeleteLabelValues(key.Namespace, key.Name)
+		}
+		delete(m.runnerSets, key)
+	}
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestReconciler_ReconcileOnce_RecordsMetrics(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	busyPod := makePod("busy", "node1", "6000m", "4Gi", corev1.PodRunning)
+	pendingPod := makePod("pending", "", "500m", "1Gi", corev1.PodPending)
+	runnerPod := makePodWithLabels("runner", "node1", "1000m", "2Gi", corev1.PodRunning, map[string]string{
+		"actions.github.com/scale-set-name": "ci",
+	})
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+	runnerSet.Status.CurrentRunners = 3
+
+	k8sClient := newFakeClient(t, &node, &busyPod, &pendingPod, &runnerPod, runnerSet)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	metrics := reconciler.metrics
+	capacityTests := []struct {
+		name      string
+		collector prometheus.Collector
+		want      float64
+	}{
+		{name: "total cpu", collector: metrics.capacityCPUCores.WithLabelValues(capacityTypeTotal), want: 10},
+		{name: "used cpu", collector: metrics.capacityCPUCores.WithLabelValues(capacityTypeUsed), want: 6},
+		{name: "excluded cpu", collector: metrics.capacityCPUCores.WithLabelValues(capacityTypeExcluded), want: 1},
+		{name: "reserved cpu", collector: metrics.capacityCPUCores.WithLabelValues(capacityTypeReserved), want: 0.4},
+		{name: "unscheduled cpu", collector: metrics.capacityCPUCores.WithLabelValues(capacityTypeUnscheduled), want: 0.5},
+		{name: "available cpu", collector: metrics.capacityCPUCores.WithLabelValues(capacityTypeAvailable), want: 3.6},
+		{name: "excluded memory", collector: metrics.capacityMemoryBytes.WithLabelValues(capacityTypeExcluded), want: 2 * 1024 * 1024 * 1024},
+		{name: "unscheduled memory", collector: metrics.capacityMemoryBytes.WithLabelValues(capacityTypeUnscheduled), want: 1024 * 1024 * 1024},
+		{name: "unscheduled pods", collector: metrics.capacityPods.WithLabelValues(capacityTypeUnscheduled), want: 1},
+	}
+	for _, tt := range capacityTests {
+		if got := testutil.ToFloat64(tt.collector); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Only 1 runner fits (3.6 CPUs), but maxRunners is raised to the 3 current runners
+	if got := testutil.ToFloat64(metrics.computedMaxRunners.WithLabelValues("default", "ci")); got != 1 {
+		t.Errorf("computed maxRunners = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.appliedMaxRunners.WithLabelValues("default", "ci")); got != 3 {
+		t.Errorf("applied maxRunners = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(metrics.currentRunners.WithLabelValues("default", "ci")); got != 3 {
+		t.Errorf("current runners = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(metrics.safetyCaps.WithLabelValues("default", "ci")); got != 1 {
+		t.Errorf("safety caps = %v, want 1", got)
+	}
+
+	if got := testutil.CollectAndCount(metrics.reconcileDuration); got != 1 {
+		t.Errorf("reconcile duration series = %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.reconcileErrors); got != 0 {
+		t.Errorf("reconcile errors = %v, want 0", got)
+	}
+}
+
+func TestReconciler_ReconcileOnce_ForgetsRemovedRunnerSets(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node, runnerSet)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := testutil.CollectAndCount(reconciler.metrics.appliedMaxRunners); got != 1 {
+		t.Fatalf("applied maxRunners series = %d, want 1", got)
+	}
+
+	if err := k8sClient.Delete(context.Background(), getRunnerSet(t, k8sClient, "default", "ci")); err != nil {
+		t.Fatalf("failed to delete runner set: %v", err)
+	}
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := testutil.CollectAndCount(reconciler.metrics.appliedMaxRunners); got != 0 {
+		t.Errorf("applied maxRunners series after deletion = %d, want 0", got)
+	}
+}
+
+func TestReconciler_ReconcileOnce_CountsErrors(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.AllocationStrategy = "unknown"
+	reconciler := NewReconciler(newFakeClient(t, &node, runnerSet), testLogger(), cfg)
+
+	if err := reconciler.ReconcileOnce(context.Background()); err == nil {
+		t.Fatal("ReconcileOnce() error = nil, want error")
+	}
+	if got := testutil.ToFloat64(reconciler.metrics.reconcileErrors); got != 1 {
+		t.Errorf("reconcile errors = %v, want 1", got)
+	}
+}
+
+func TestMetrics_Register(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if err := NewMetrics().Register(registry); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// A second reconciler can't register the same metrics
+	if err := NewMetrics().Register(registry); err == nil {
+		t.Error("Register() of duplicate metrics error = nil, want error")
+	}
+}
+
+func TestMetrics_RecordCapacity_DroppedNodes(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.recordCapacity(&ClusterCapacity{
+		DroppedNodes: map[string]string{
+			"node1": nodeDropReasonCordoned,
+			"node2": nodeDropReasonCordoned,
+			"node3": nodeDropReasonToBeDeleted,
+		},
+	})
+
+	if got := testutil.ToFloat64(metrics.capacityDroppedNodes.WithLabelValues(nodeDropReasonCordoned)); got != 2 {
+		t.Errorf("cordoned nodes = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.capacityDroppedNodes.WithLabelValues(nodeDropReasonToBeDeleted)); got != 1 {
+		t.Errorf("to be deleted nodes = %v, want 1", got)
+	}
+
+	// Reasons no node is dropped for anymore are reset
+	metrics.recordCapacity(&ClusterCapacity{})
+	if got := testutil.ToFloat64(metrics.capacityDroppedNodes.WithLabelValues(nodeDropReasonCordoned)); got != 0 {
+		t.Errorf("cordoned nodes = %v, want 0", got)
+	}
+	if got := testutil.CollectAndCount(metrics.capacityDroppedNodes); got != len(nodeDropReasons) {
+		t.Errorf("dropped nodes series = %d, want %d", got, len(nodeDropReasons))
+	}
+}
+
+func TestMetrics_RecordCapacity_ExtendedResources(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.recordCapacity(&ClusterCapacity{
+		TotalScalar:     ScalarResources{"nvidia.com/gpu": 4, "example.com/fpga": 1, corev1.ResourceEphemeralStorage: 100, corev1.ResourcePods: 110},
+		UsedScalar:      ScalarResources{"nvidia.com/gpu": 1, corev1.ResourceEphemeralStorage: 30, corev1.ResourcePods: 12},
+		AvailableScalar: ScalarResources{"nvidia.com/gpu": 3, "example.com/fpga": 1, corev1.ResourceEphemeralStorage: 70, corev1.ResourcePods: 98},
+	})
+
+	// Ephemeral storage and pods have their own metrics
+	if got := testutil.ToFloat64(metrics.capacityEphemeralStorageBytes.WithLabelValues(capacityTypeAvailable)); got != 70 {
+		t.Errorf("available ephemeral storage = %v, want 70", got)
+	}
+	if got := testutil.ToFloat64(metrics.capacityPods.WithLabelValues(capacityTypeAvailable)); got != 98 {
+		t.Errorf("available pods = %v, want 98", got)
+	}
+	if got := testutil.CollectAndCount(metrics.capacityExtendedResource); got != 6 {
+		t.Errorf("extended resource series = %d, want 6", got)
+	}
+
+	if got := testutil.ToFloat64(metrics.capacityExtendedResource.WithLabelValues("nvidia.com/gpu", capacityTypeUsed)); got != 1 {
+		t.Errorf("used nvidia.com/gpu = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.capacityExtendedResource.WithLabelValues("nvidia.com/gpu", capacityTypeAvailable)); got != 3 {
+		t.Errorf("available nvidia.com/gpu = %v, want 3", got)
+	}
+
+	// Resources no node offers anymore are removed
+	metrics.recordCapacity(&ClusterCapacity{
+		TotalScalar:     ScalarResources{"nvidia.com/gpu": 4},
+		AvailableScalar: ScalarResources{"nvidia.com/gpu": 4},
+	})
+	if got := testutil.CollectAndCount(metrics.capacityExtendedResource); got != 3 {
+		t.Errorf("extended resource series = %d, want 3", got)
+	}
+}
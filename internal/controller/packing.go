@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// nodeSlot tracks the free capacity of a node while runners are being placed onto it
+type nodeSlot struct {
+	node        *NodeCapacity
+	cpuMillis   int64
+	memoryBytes int64
+	scalar      ScalarResources
+}
+
+// FitToNodes limits allocations to the number of runners that can actually be placed onto nodes.
+//
+// A cluster-wide sum of free capacity overstates what the scheduler can place: ten nodes with
+// 1.5 free cores each add up to 15 cores, but a 4-core runner fits on none of them. Runners are
+// therefore bin-packed onto individual nodes in priority order, each onto the node that leaves
+// the least free CPU (best fit). Capacity left over after packing is offered to runner sets that
+// are below their configured max, again in priority order. Runners are only placed onto
+// nodes their pod template can be scheduled onto.
+//
+// Minimum runner guarantees are kept even when they don't fit, matching the allocation strategies.
+func (a *Allocator) FitToNodes(runnerSets []*RunnerSetResources, allocations []RunnerSetAllocation, nodes []NodeCapacity) []RunnerSetAllocation {
+	slots := make([]nodeSlot, 0, len(nodes))
+	for i := range nodes {
+		slots = append(slots, nodeSlot{
+			node:        &nodes[i],
+			cpuMillis:   nodes[i].AvailableCPUMillis,
+			memoryBytes: nodes[i].AvailableMemoryBytes,
+			scalar:      nodes[i].AvailableScalar.clone(),
+		})
+	}
+	// Sort by name for deterministic placement
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].node.Name < slots[j].node.Name
+	})
+
+	targets := make(map[types.NamespacedName]int, len(allocations))
+	for _, alloc := range allocations {
+		targets[alloc.Key()] = alloc.MaxRunners
+	}
+	minRunners := make(map[types.NamespacedName]int, len(runnerSets))
+	eligible := make(map[types.NamespacedName][]bool, len(runnerSets))
+	for _, rs := range runnerSets {
+		minRunners[rs.Key()] = rs.MinRunners
+		eligible[rs.Key()] = make([]bool, len(slots))
+		for i := range slots {
+			eligible[rs.Key()][i] = canScheduleOn(rs, slots[i].node)
+		}
+	}
+
+	sortedRunnerSets := make([]*RunnerSetResources, len(runnerSets))
+	copy(sortedRunnerSets, runnerSets)
+	sort.Slice(sortedRunnerSets, func(i, j int) bool {
+		return higherPriority(sortedRunnerSets[i], sortedRunnerSets[j])
+	})
+
+	// First pass: place the runners allocated by the strategy
+	placed := make(map[types.NamespacedName]int, len(runnerSets))
+	for _, rs := range sortedRunnerSets {
+		for placed[rs.Key()] < targets[rs.Key()] && placeRunner(slots, rs, eligible[rs.Key()]) {
+			placed[rs.Key()]++
+		}
+	}
+
+	// Second pass: offer capacity left over by fragmentation to runner sets below their cap
+	for _, rs := range sortedRunnerSets {
+		limit := rs.maxRunnersCap()
+		for (limit < 0 || placed[rs.Key()] < limit) && placeRunner(slots, rs, eligible[rs.Key()]) {
+			placed[rs.Key()]++
+		}
+	}
+
+	results := make([]RunnerSetAllocation, 0, len(allocations))
+	for _, alloc := range allocations {
+		target := alloc.MaxRunners
+		maxRunners := placed[alloc.Key()]
+
+		// Keep the minimum runners guaranteed by the strategy
+		maxRunners = max(maxRunners, min(target, minRunners[alloc.Key()]))
+
+		a.logger.Debug("bin-packed runner set",
+			"namespace", alloc.Namespace,
+			"name", alloc.Name,
+			"target_max_runners", target,
+			"placed_runners", placed[alloc.Key()],
+			"max_runners", maxRunners)
+
+		alloc.MaxRunners = maxRunners
+		results = append(results, alloc)
+	}
+
+	return results
+}
+
+// placeRunner places a single runner onto the best fitting eligible node and reports whether it fit
+func placeRunner(slots []nodeSlot, rs *RunnerSetResources, eligible []bool) bool {
+	if rs.CPUMillis <= 0 || rs.MemoryBytes <= 0 {
+		return false
+	}
+
+	best := -1
+	for i := range slots {
+		if !eligible[i] {
+			continue
+		}
+		if slots[i].cpuMillis < rs.CPUMillis || slots[i].memoryBytes < rs.MemoryBytes || !fitsScalar(slots[i].scalar, rs.ScalarResources) {
+			continue
+		}
+		if best == -1 || slots[i].cpuMillis < slots[best].cpuMillis {
+			best = i
+		}
+	}
+	if best == -1 {
+		return false
+	}
+
+	slots[best].cpuMillis -= rs.CPUMillis
+	slots[best].memoryBytes -= rs.MemoryBytes
+	slots[best].scalar.add(rs.ScalarResources, -1)
+	return true
+}
+
+// fitsScalar reports whether the requested extended resources fit into the available ones
+func fitsScalar(available, requests ScalarResources) bool {
+	for name, request := range requests {
+		if available[name] < request {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestAllocator_FitToNodes(t *testing.T) {
+	// makeNodes creates count nodes with the same available capacity
+	makeNodes := func(count int, cpuMillis, memoryBytes int64) []NodeCapacity {
+		nodes := make([]NodeCapacity, 0, count)
+		for i := range count {
+			nodes = append(nodes, NodeCapacity{
+				Name:                 string(rune('a' + i)),
+				AvailableCPUMillis:   cpuMillis,
+				AvailableMemoryBytes: memoryBytes,
+			})
+		}
+		return nodes
+	}
+
+	tests := []struct {
+		name        string
+		runnerSets  []*RunnerSetResources
+		allocations []RunnerSetAllocation
+		nodes       []NodeCapacity
+		want        map[string]int // name -> maxRunners
+	}{
+		{
+			name: "fragmented capacity fits no large runner",
+			runnerSets: []*RunnerSetResources{
+				{Name: "xl", CPUMillis: 4000, MemoryBytes: 8 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 10},
+			},
+			allocations: []RunnerSetAllocation{
+				{Name: "xl", MaxRunners: 3}, // 15 CPUs summed across nodes
+			},
+			nodes: makeNodes(10, 1500, 16*1024*1024*1024),
+			want: map[string]int{
+				"xl": 0, // No single node has 4 free CPUs
+			},
+		},
+		{
+			name: "leftover capacity goes to runner sets that fit",
+			runnerSets: []*RunnerSetResources{
+				{Name: "xl", CPUMillis: 4000, MemoryBytes: 8 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 10},
+				{Name: "small", CPUMillis: 1000, MemoryBytes: 2 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+			},
+			allocations: []RunnerSetAllocation{
+				{Name: "xl", MaxRunners: 2},
+				{Name: "small", MaxRunners: 7},
+			},
+			nodes: makeNodes(10, 1500, 16*1024*1024*1024),
+			want: map[string]int{
+				"xl":    0,  // Doesn't fit anywhere
+				"small": 10, // 7 allocated + 3 from leftover capacity, one per node
+			},
+		},
+		{
+			name: "runners fit exactly",
+			runnerSets: []*RunnerSetResources{
+				{Name: "xl", CPUMillis: 4000, MemoryBytes: 8 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 4},
+			},
+			allocations: []RunnerSetAllocation{
+				{Name: "xl", MaxRunners: 4},
+			},
+			nodes: makeNodes(2, 8000, 16*1024*1024*1024),
+			want: map[string]int{
+				"xl": 4, // Two runners per node
+			},
+		},
+		{
+			name: "memory fragmentation limits placement",
+			runnerSets: []*RunnerSetResources{
+				{Name: "mem-heavy", CPUMillis: 1000, MemoryBytes: 12 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 10},
+			},
+			allocations: []RunnerSetAllocation{
+				{Name: "mem-heavy", MaxRunners: 2},
+			},
+			nodes: makeNodes(3, 8000, 8*1024*1024*1024),
+			want: map[string]int{
+				"mem-heavy": 0, // 24Gi summed, but no node has 12Gi free
+			},
+		},
+		{
+			name: "best fit keeps room for large runners",
+			runnerSets: []*RunnerSetResources{
+				{Name: "small", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 1},
+				{Name: "large", CPUMillis: 4000, MemoryBytes: 4 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 1},
+			},
+			allocations: []RunnerSetAllocation{
+				{Name: "small", MaxRunners: 1},
+				{Name: "large", MaxRunners: 1},
+			},
+			nodes: []NodeCapacity{
+				{Name: "big", AvailableCPUMillis: 4000, AvailableMemoryBytes: 8 * 1024 * 1024 * 1024},
+				{Name: "tiny", AvailableCPUMillis: 1000, AvailableMemoryBytes: 8 * 1024 * 1024 * 1024},
+			},
+			want: map[string]int{
+				"small": 1, // Placed on the tiny node
+				"large": 1, // Big node is still free
+			},
+		},
+		{
+			name: "minimum runners are kept when they don't fit",
+			runnerSets: []*RunnerSetResources{
+				{Name: "xl", CPUMillis: 4000, MemoryBytes: 8 * 1024 * 1024 * 1024, Priority: 10, MinRunners: 1, ConfiguredMax: 10},
+			},
+			allocations: []RunnerSetAllocation{
+				{Name: "xl", MaxRunners: 2},
+			},
+			nodes: makeNodes(4, 2000, 16*1024*1024*1024),
+			want: map[string]int{
+				"xl": 1, // Minimum guarantee
+			},
+		},
+		{
+			name: "leftover capacity respects configured max",
+			runnerSets: []*RunnerSetResources{
+				{Name: "small", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 3},
+			},
+			allocations: []RunnerSetAllocation{
+				{Name: "small", MaxRunners: 2},
+			},
+			nodes: makeNodes(2, 4000, 8*1024*1024*1024),
+			want: map[string]int{
+				"small": 3, // Capped at ConfiguredMax
+			},
+		},
+		{
+			name: "extended resources limit placement",
+			runnerSets: []*RunnerSetResources{
+				{Name: "gpu", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, ScalarResources: ScalarResources{"nvidia.com/gpu": 1}, Priority: 1, ConfiguredMax: 10},
+			},
+			allocations: []RunnerSetAllocation{
+				{Name: "gpu", MaxRunners: 5},
+			},
+			nodes: []NodeCapacity{
+				{
+					Name: "gpu-a", AvailableCPUMillis: 8000, AvailableMemoryBytes: 16 * 1024 * 1024 * 1024,
+					AllocatableScalar: ScalarResources{"nvidia.com/gpu": 2}, AvailableScalar: ScalarResources{"nvidia.com/gpu": 2},
+				},
+				{
+					Name: "gpu-b", AvailableCPUMillis: 8000, AvailableMemoryBytes: 16 * 1024 * 1024 * 1024,
+					AllocatableScalar: ScalarResources{"nvidia.com/gpu": 2}, AvailableScalar: ScalarResources{"nvidia.com/gpu": 1},
+				},
+				{Name: "cpu", AvailableCPUMillis: 8000, AvailableMemoryBytes: 16 * 1024 * 1024 * 1024},
+			},
+			want: map[string]int{
+				"gpu": 3, // One runner per free GPU, none on the node without GPUs
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+			allocator := NewAllocator(logger)
+
+			allocations := allocator.FitToNodes(tt.runnerSets, tt.allocations, tt.nodes)
+
+			got := make(map[string]int)
+			for _, alloc := range allocations {
+				got[alloc.Name] = alloc.MaxRunners
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("got %d allocations, want %d: %v", len(got), len(tt.want), got)
+			}
+			for name, wantMax := range tt.want {
+				if gotMax := got[name]; gotMax != wantMax {
+					t.Errorf("allocation for %s = %v, want %v", name, gotMax, wantMax)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocator_FitToNodesRespectsEligibility(t *testing.T) {
+	nodes := []NodeCapacity{
+		{Name: "default-1", Labels: map[string]string{"pool": "default"}, AvailableCPUMillis: 8000, AvailableMemoryBytes: 16 * 1024 * 1024 * 1024},
+		{Name: "xl-1", Labels: map[string]string{"pool": "xl"}, AvailableCPUMillis: 8000, AvailableMemoryBytes: 16 * 1024 * 1024 * 1024},
+	}
+	runnerSets := []*RunnerSetResources{
+		{Name: "xl", CPUMillis: 4000, MemoryBytes: 4 * 1024 * 1024 * 1024, Priority: 10, ConfiguredMax: 10, NodeSelector: map[string]string{"pool": "xl"}},
+		{Name: "default", CPUMillis: 2000, MemoryBytes: 2 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 10, NodeSelector: map[string]string{"pool": "default"}},
+	}
+	allocations := []RunnerSetAllocation{
+		{Name: "xl", MaxRunners: 4},
+		{Name: "default", MaxRunners: 4},
+	}
+
+	allocator := NewAllocator(testLogger())
+	got := map[types.NamespacedName]int{}
+	for _, alloc := range allocator.FitToNodes(runnerSets, allocations, nodes) {
+		got[alloc.Key()] = alloc.MaxRunners
+	}
+
+	// Each runner set only fits onto its own node: 8 CPUs / 4 CPUs and 8 CPUs / 2 CPUs
+	if got[types.NamespacedName{Name: "xl"}] != 2 {
+		t.Errorf("allocation for xl = %v, want 2", got[types.NamespacedName{Name: "xl"}])
+	}
+	if got[types.NamespacedName{Name: "default"}] != 4 {
+		t.Errorf("allocation for default = %v, want 4", got[types.NamespacedName{Name: "default"}])
+	}
+}
@@ -2,11 +2,18 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
@@ -16,47 +23,114 @@ import (
 type Reconciler struct {
 	client     client.Client
 	logger     *slog.Logger
-	config     *config.Config
 	calculator *CapacityCalculator
 	allocator  *Allocator
+	metrics    *Metrics
+
+	// config is swapped when the configuration is reloaded (see UpdateConfig)
+	config atomic.Pointer[config.Config]
+
+	// triggers receives reconciliation requests from watch events
+	triggers chan struct{}
+
+	// recorder records Events on runner sets (see SetupWithManager)
+	recorder record.EventRecorder
+
+	// elected is closed once this replica may reconcile (see SetupWithManager)
+	elected <-chan struct{}
+
+	// lastActive is when the loop started or last completed a reconciliation (unix nanoseconds),
+	// reconciled whether a reconciliation succeeded. Both are read by the health probes.
+	lastActive atomic.Int64
+	reconciled atomic.Bool
 }
 
 // NewReconciler creates a new reconciler
 func NewReconciler(client client.Client, logger *slog.Logger, cfg *config.Config) *Reconciler {
-	calculator := NewCapacityCalculator(client, logger, cfg.CPUBufferPercent, cfg.MemoryBufferPercent)
+	calculator := NewCapacityCalculator(client, logger)
 	allocator := NewAllocator(logger)
 
-	return &Reconciler{
+	reconciler := &Reconciler{
 		client:     client,
 		logger:     logger,
-		config:     cfg,
 		calculator: calculator,
 		allocator:  allocator,
+		metrics:    NewMetrics(),
+		triggers:   make(chan struct{}, 1),
 	}
+	reconciler.config.Store(cfg)
+	return reconciler
 }
 
-// Run starts the reconciliation loop
+// UpdateConfig swaps the configuration used by subsequent reconciliations and triggers a reconciliation,
+// so changed settings take effect right away. Settings read at startup, like leader election and the
+// watched resources, are not affected.
+func (r *Reconciler) UpdateConfig(cfg *config.Config) {
+	r.config.Store(cfg)
+	r.Trigger("configuration changed")
+}
+
+// capacityBuffers returns the safety buffers of a configuration
+func capacityBuffers(cfg *config.Config) CapacityBuffers {
+	return CapacityBuffers{
+		CPUPercent:             cfg.CPUBufferPercent,
+		MemoryPercent:          cfg.MemoryBufferPercent,
+		CPUReserveMillis:       cfg.CPUReserve.MilliValue(),
+		MemoryReserveBytes:     cfg.MemoryReserve.Value(),
+		NodeCPUReserveMillis:   cfg.NodeCPUReserve.MilliValue(),
+		NodeMemoryReserveBytes: cfg.NodeMemoryReserve.Value(),
+		ReserveUnscheduled:     cfg.ReserveUnscheduledPods,
+	}
+}
+
+// Run starts the reconciliation loop.
+//
+// Reconciliations are triggered by watch events (see Trigger). Events are debounced: the first event
+// starts a timer of ReconcileDebounce, and all events until it fires are handled by a single
+// reconciliation. As a fallback for missed events, a resync runs when there was no reconciliation
+// for ReconcileInterval.
 func (r *Reconciler) Run(ctx context.Context) error {
+	cfg := r.config.Load()
 	r.logger.Info("starting reconciliation loop",
-		"interval", r.config.ReconcileInterval,
-		"namespaces", r.config.Namespaces,
-		"dry_run", r.config.DryRun)
+		"resync_interval", cfg.ReconcileInterval,
+		"debounce", cfg.ReconcileDebounce,
+		"capacity_mode", cfg.CapacityMode,
+		"allocation_strategy", cfg.AllocationStrategy,
+		"demand_aware", cfg.DemandAware,
+		"namespaces", cfg.Namespaces,
+		"dry_run", cfg.DryRun)
+
+	r.markActive()
 
 	// Run initial reconciliation immediately
 	if err := r.ReconcileOnce(ctx); err != nil {
 		r.logger.Error("initial reconciliation failed", "error", err)
 	}
 
-	// Start periodic reconciliation
-	ticker := time.NewTicker(r.config.ReconcileInterval)
-	defer ticker.Stop()
+	// Start periodic resync
+	resync := time.NewTicker(r.config.Load().ReconcileInterval)
+	defer resync.Stop()
+
+	// debounced is only set while a debounce timer is running
+	var debounced <-chan time.Time
 
 	for {
 		select {
 		case <-ctx.Done():
 			r.logger.Info("reconciliation loop stopped")
 			return ctx.Err()
-		case <-ticker.C:
+		case <-r.triggers:
+			if debounced == nil {
+				debounced = time.After(r.config.Load().ReconcileDebounce)
+			}
+		case <-debounced:
+			debounced = nil
+			if err := r.ReconcileOnce(ctx); err != nil {
+				r.logger.Error("reconciliation failed", "error", err)
+			}
+			resync.Reset(r.config.Load().ReconcileInterval)
+		case <-resync.C:
+			r.logger.Debug("resyncing")
 			if err := r.ReconcileOnce(ctx); err != nil {
 				r.logger.Error("reconciliation failed", "error", err)
 			}
@@ -64,16 +138,57 @@ func (r *Reconciler) Run(ctx context.Context) error {
 	}
 }
 
+// Start implements manager.Runnable, so the reconciliation loop runs once the manager's caches are synced
+func (r *Reconciler) Start(ctx context.Context) error {
+	if err := r.Run(ctx); err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Only the leader reconciles,
+// except in dry-run mode: nothing is changed, so dry-run replicas can observe alongside the leader.
+func (r *Reconciler) NeedLeaderElection() bool {
+	return !r.config.Load().DryRun
+}
+
+// Trigger requests a reconciliation. It never blocks; triggers are coalesced until the reconciliation runs.
+func (r *Reconciler) Trigger(reason string) {
+	select {
+	case r.triggers <- struct{}{}:
+		r.logger.Debug("reconciliation triggered", "reason", reason)
+	default:
+		// A reconciliation is already pending
+	}
+}
+
 // ReconcileOnce performs a single reconciliation cycle
 func (r *Reconciler) ReconcileOnce(ctx context.Context) error {
 	startTime := time.Now()
+	err := r.reconcile(ctx)
+	r.metrics.reconcileDuration.Observe(time.Since(startTime).Seconds())
+	r.markActive()
+	if err != nil {
+		r.metrics.reconcileErrors.Inc()
+		return err
+	}
+	r.reconciled.Store(true)
+	return nil
+}
+
+// reconcile calculates and applies maxRunners for all enabled runner sets.
+// The configuration is read once, so a reload during the cycle doesn't mix settings.
+func (r *Reconciler) reconcile(ctx context.Context) error {
+	startTime := time.Now()
+	cfg := r.config.Load()
 	r.logger.Info("reconciliation started")
 
 	// 1. Calculate available cluster capacity
-	capacity, err := r.calculator.Calculate(ctx)
+	capacity, err := r.calculator.Calculate(ctx, capacityBuffers(cfg), cfg.NodeFilter)
 	if err != nil {
 		return fmt.Errorf("failed to calculate capacity: %w", err)
 	}
+	r.metrics.recordCapacity(capacity)
 
 	r.logger.Info("cluster capacity calculated",
 		"total_cpu_millis", capacity.TotalCPUMillis,
@@ -84,13 +199,25 @@ func (r *Reconciler) ReconcileOnce(ctx context.Context) error {
 		"used_cpu_cores", float64(capacity.UsedCPUMillis)/1000,
 		"used_memory_bytes", capacity.UsedMemoryBytes,
 		"used_memory_gb", float64(capacity.UsedMemoryBytes)/(1024*1024*1024),
+		"reserved_cpu_millis", capacity.ReservedCPUMillis,
+		"reserved_memory_bytes", capacity.ReservedMemoryBytes,
+		"unscheduled_cpu_millis", capacity.UnscheduledCPUMillis,
+		"unscheduled_memory_bytes", capacity.UnscheduledMemoryBytes,
 		"available_cpu_millis", capacity.AvailableCPUMillis,
 		"available_cpu_cores", float64(capacity.AvailableCPUMillis)/1000,
 		"available_memory_bytes", capacity.AvailableMemoryBytes,
-		"available_memory_gb", float64(capacity.AvailableMemoryBytes)/(1024*1024*1024))
+		"available_memory_gb", float64(capacity.AvailableMemoryBytes)/(1024*1024*1024),
+		"total_ephemeral_storage_bytes", capacity.TotalScalar[corev1.ResourceEphemeralStorage],
+		"used_ephemeral_storage_bytes", capacity.UsedScalar[corev1.ResourceEphemeralStorage],
+		"available_ephemeral_storage_bytes", capacity.AvailableScalar[corev1.ResourceEphemeralStorage],
+		"total_pods", capacity.TotalScalar[corev1.ResourcePods],
+		"used_pods", capacity.UsedScalar[corev1.ResourcePods],
+		"available_pods", capacity.AvailableScalar[corev1.ResourcePods],
+		"total_scalar", capacity.TotalScalar,
+		"available_scalar", capacity.AvailableScalar)
 
 	// 2. List all AutoscalingRunnerSets
-	runnerSets, err := r.listRunnerSets(ctx)
+	runnerSets, err := r.listRunnerSets(ctx, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to list runner sets: %w", err)
 	}
@@ -99,57 +226,102 @@ func (r *Reconciler) ReconcileOnce(ctx context.Context) error {
 
 	if len(runnerSets) == 0 {
 		r.logger.Warn("no runner sets found")
+		r.metrics.forgetRunnerSets(nil)
+		r.metrics.invalidRunnerSets.Set(0)
 		return nil
 	}
 
 	// 3. Extract resource requirements from enabled runner sets
 	enabledRunnerSets := make([]*RunnerSetResources, 0, len(runnerSets))
+	invalidCount := 0
 	for i := range runnerSets {
 		resources, err := ExtractRunnerSetResources(&runnerSets[i])
-		if err != nil {
+		if errors.Is(err, ErrAutoscalingNotEnabled) {
 			r.logger.Debug("skipping runner set",
+				"namespace", runnerSets[i].Namespace,
 				"name", runnerSets[i].Name,
 				"reason", err.Error())
 			continue
 		}
+		if err != nil {
+			// The runner set opted in, so a misconfiguration must not leave it silently unmanaged
+			invalidCount++
+			r.logger.Error("skipping runner set with invalid configuration",
+				"namespace", runnerSets[i].Namespace,
+				"name", runnerSets[i].Name,
+				"error", err)
+			r.recordEvent(cfg, &runnerSets[i], corev1.EventTypeWarning, eventReasonInvalidAnnotation,
+				"Autoscaling skipped: %v", err)
+			r.writeStatus(ctx, cfg, &runnerSets[i], &RunnerSetStatus{
+				LastReconcileTime: metav1.NewTime(startTime),
+				Error:             err.Error(),
+			})
+			continue
+		}
 
 		r.logger.Info("runner set enabled for autoscaling",
+			"namespace", resources.Namespace,
 			"name", resources.Name,
 			"cpu_millis", resources.CPUMillis,
 			"memory_bytes", resources.MemoryBytes,
 			"priority", resources.Priority,
 			"configured_max", resources.ConfiguredMax)
 
+		// Record the operator-declared cap on first adoption, before spec.maxRunners is overwritten.
+		// Without it, the next cycle would mistake the patched value for the cap.
+		if _, ok := runnerSets[i].Annotations[config.AnnotationMaxRunners]; !ok {
+			if err := r.recordConfiguredMax(ctx, cfg, &runnerSets[i], resources.ConfiguredMax); err != nil {
+				r.logger.Error("failed to record configured maxRunners, skipping runner set",
+					"namespace", resources.Namespace,
+					"name", resources.Name,
+					"error", err)
+				continue
+			}
+		}
+
 		enabledRunnerSets = append(enabledRunnerSets, resources)
 	}
 
-	r.logger.Info("enabled runner sets", "count", len(enabledRunnerSets))
+	r.metrics.invalidRunnerSets.Set(float64(invalidCount))
+	r.logger.Info("enabled runner sets", "count", len(enabledRunnerSets), "invalid", invalidCount)
 
 	if len(enabledRunnerSets) == 0 {
 		r.logger.Warn("no runner sets enabled for autoscaling (missing annotation)")
+		r.metrics.forgetRunnerSets(nil)
 		return nil
 	}
 
-	// 4. Calculate new maxRunners for each runner set using fair share allocation
-	allocations, err := r.allocator.AllocateFairShare(enabledRunnerSets, capacity.AvailableCPUMillis, capacity.AvailableMemoryBytes)
+	// Limit runner sets to their demand, so idle runner sets don't hold capacity that busy ones need
+	if cfg.DemandAware {
+		r.applyDemandLimits(ctx, cfg, runnerSets, enabledRunnerSets)
+	}
+
+	// 4. Calculate new maxRunners for each runner set using the configured allocation strategy
+	allocations, err := r.allocate(cfg, enabledRunnerSets, capacity)
 	if err != nil {
 		return fmt.Errorf("failed to allocate runners: %w", err)
 	}
 
 	// 5. Apply the new maxRunners values
+	// Runner sets are matched by namespace and name, as names are only unique within a namespace
+	runnerSetsByKey := make(map[types.NamespacedName]*actionsv1alpha1.AutoscalingRunnerSet, len(runnerSets))
+	for i := range runnerSets {
+		runnerSetsByKey[client.ObjectKeyFromObject(&runnerSets[i])] = &runnerSets[i]
+	}
+	resourcesByKey := make(map[types.NamespacedName]*RunnerSetResources, len(enabledRunnerSets))
+	for _, rs := range enabledRunnerSets {
+		resourcesByKey[rs.Key()] = rs
+	}
+
 	updatedCount := 0
+	recorded := make(map[types.NamespacedName]struct{}, len(allocations))
 	for _, alloc := range allocations {
 		// Find the corresponding runner set
-		var runnerSet *actionsv1alpha1.AutoscalingRunnerSet
-		for i := range runnerSets {
-			if runnerSets[i].Name == alloc.Name {
-				runnerSet = &runnerSets[i]
-				break
-			}
-		}
-
-		if runnerSet == nil {
-			r.logger.Warn("runner set not found for allocation", "name", alloc.Name)
+		runnerSet, ok := runnerSetsByKey[alloc.Key()]
+		if !ok {
+			r.logger.Warn("runner set not found for allocation",
+				"namespace", alloc.Namespace,
+				"name", alloc.Name)
 			continue
 		}
 
@@ -165,27 +337,53 @@ func (r *Reconciler) ReconcileOnce(ctx context.Context) error {
 		// Safety check: never scale below currently running runners
 		// This prevents killing active runners that are processing jobs
 		newMax := alloc.MaxRunners
+		limitedBy := limitingFactor(resourcesByKey[alloc.Key()], newMax, capacity)
+		safetyCapped := false
 		if newMax < currentlyRunning {
 			r.logger.Info("capping maxRunners to current running count (safety)",
+				"namespace", alloc.Namespace,
 				"name", alloc.Name,
 				"calculated_max", alloc.MaxRunners,
 				"currently_running", currentlyRunning,
 				"new_max", currentlyRunning)
 			newMax = currentlyRunning
+			limitedBy = limitingFactorRunningJobs
+			safetyCapped = true
+			r.metrics.recordSafetyCap(alloc.Key())
+			r.recordEvent(cfg, runnerSet, corev1.EventTypeWarning, eventReasonSafetyCapApplied,
+				"Computed maxRunners %d is below the %d current runners, keeping %d to protect running jobs",
+				alloc.MaxRunners, currentlyRunning, newMax)
+		}
+
+		// Until the update succeeds, the current maxRunners stays applied
+		r.metrics.recordRunnerSet(alloc.Key(), alloc.MaxRunners, currentMax, currentlyRunning)
+		recorded[alloc.Key()] = struct{}{}
+
+		computedMax, fairShare := alloc.MaxRunners, alloc.FairShare
+		status := &RunnerSetStatus{
+			LastReconcileTime:  metav1.NewTime(startTime),
+			ComputedMaxRunners: &computedMax,
+			AppliedMaxRunners:  &newMax,
+			LimitedBy:          limitedBy,
+			FairShare:          &fairShare,
+			SafetyCapped:       safetyCapped,
 		}
 
 		if currentMax == newMax {
 			r.logger.Debug("maxRunners unchanged",
+				"namespace", alloc.Namespace,
 				"name", alloc.Name,
 				"max_runners", newMax,
 				"currently_running", currentlyRunning)
+			r.writeStatus(ctx, cfg, runnerSet, status)
 			continue
 		}
 
 		// Update the maxRunners
-		if r.config.DryRun {
+		if cfg.DryRun {
 			// In dry-run mode, just log what would have been changed
 			r.logger.Warn("[DRY-RUN] would update maxRunners",
+				"namespace", alloc.Namespace,
 				"name", alloc.Name,
 				"old_max", currentMax,
 				"new_max", newMax,
@@ -193,25 +391,39 @@ func (r *Reconciler) ReconcileOnce(ctx context.Context) error {
 			updatedCount++
 		} else {
 			// Actually update the resource
-			if err := r.updateRunnerSet(ctx, runnerSet, newMax); err != nil {
+			if err := r.updateRunnerSet(ctx, runnerSet, newMax, status); err != nil {
 				r.logger.Error("failed to update runner set",
+					"namespace", alloc.Namespace,
 					"name", alloc.Name,
 					"error", err)
+				r.metrics.recordUpdateError(alloc.Key())
 				continue
 			}
+			r.metrics.recordRunnerSet(alloc.Key(), alloc.MaxRunners, newMax, currentlyRunning)
+
+			reason := eventReasonScaledUp
+			if newMax < currentMax {
+				reason = eventReasonScaledDown
+			}
+			r.recordEvent(cfg, runnerSet, corev1.EventTypeNormal, reason,
+				"Scaled maxRunners from %d to %d (limited by %s)", currentMax, newMax, limitedBy)
 
 			r.logger.Info("updated maxRunners",
+				"namespace", alloc.Namespace,
 				"name", alloc.Name,
 				"old_max", currentMax,
 				"new_max", newMax,
-				"currently_running", currentlyRunning)
+				"currently_running", currentlyRunning,
+				"limited_by", limitedBy)
 
 			updatedCount++
 		}
 	}
 
+	r.metrics.forgetRunnerSets(recorded)
+
 	elapsed := time.Since(startTime)
-	if r.config.DryRun {
+	if cfg.DryRun {
 		r.logger.Info("reconciliation completed (dry-run)",
 			"duration", elapsed,
 			"runner_sets_total", len(runnerSets),
@@ -228,12 +440,112 @@ func (r *Reconciler) ReconcileOnce(ctx context.Context) error {
 	return nil
 }
 
+// allocate calculates maxRunners for the runner sets, respecting the nodes each runner set can be scheduled onto.
+//
+// Runner sets are split into pools of sets whose eligible nodes overlap, and capacity is allocated separately
+// for each pool, so runner sets pinned to different node pools don't take each other's share.
+func (r *Reconciler) allocate(cfg *config.Config, runnerSets []*RunnerSetResources, capacity *ClusterCapacity) ([]RunnerSetAllocation, error) {
+	strategy, err := NewAllocationStrategy(cfg.AllocationStrategy, r.allocator)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := partitionNodePools(runnerSets, capacity.Nodes)
+
+	allocations := make([]RunnerSetAllocation, 0, len(runnerSets))
+	for _, pool := range pools {
+		// A pool spanning all nodes uses the cluster-wide figures, other pools sum their nodes. Both keep
+		// the cluster reserves and reserved unscheduled pods free.
+		availableCPU, availableMemory, availableScalar := capacity.AvailableCPUMillis, capacity.AvailableMemoryBytes, capacity.AvailableScalar
+		if len(pool.nodes) < len(capacity.Nodes) {
+			availableCPU, availableMemory, availableScalar = sumAvailable(pool.nodes)
+		}
+
+		if len(pool.nodes) == 0 {
+			for _, rs := range pool.runnerSets {
+				r.logger.Warn("runner set cannot be scheduled onto any ready node",
+					"namespace", rs.Namespace,
+					"name", rs.Name)
+			}
+		}
+
+		r.logger.Debug("allocating node pool",
+			"runner_sets", len(pool.runnerSets),
+			"nodes", len(pool.nodes),
+			"available_cpu_millis", availableCPU,
+			"available_memory_bytes", availableMemory,
+			"available_scalar", availableScalar)
+
+		poolAllocations, err := strategy.Allocate(pool.runnerSets, availableCPU, availableMemory, availableScalar)
+		if err != nil {
+			return nil, err
+		}
+		shares := r.fairShares(cfg, pool.runnerSets)
+		for i := range poolAllocations {
+			poolAllocations[i].FairShare = shares[poolAllocations[i].Key()]
+		}
+		allocations = append(allocations, poolAllocations...)
+	}
+
+	// In node capacity mode, limit allocations to the runners that fit onto individual eligible nodes
+	if cfg.CapacityMode == config.CapacityModeNode {
+		return r.allocator.FitToNodes(runnerSets, allocations, capacity.Nodes), nil
+	}
+
+	// A runner set sharing a pool may only reach some of its nodes, so cap it by the capacity it can reach
+	runnerSetsByKey := make(map[types.NamespacedName]*RunnerSetResources, len(runnerSets))
+	for _, rs := range runnerSets {
+		runnerSetsByKey[rs.Key()] = rs
+	}
+	for i := range allocations {
+		rs := runnerSetsByKey[allocations[i].Key()]
+		nodes := eligibleNodes(rs, capacity.Nodes)
+		if len(nodes) == len(capacity.Nodes) {
+			continue
+		}
+
+		eligibleCPU, eligibleMemory, eligibleScalar := sumAvailable(nodes)
+		maxRunners := max(r.allocator.calculateMaxRunners(rs, eligibleCPU, eligibleMemory, eligibleScalar), min(allocations[i].MaxRunners, rs.MinRunners))
+		if maxRunners < allocations[i].MaxRunners {
+			r.logger.Debug("capping allocation to eligible nodes",
+				"namespace", rs.Namespace,
+				"name", rs.Name,
+				"eligible_nodes", len(nodes),
+				"calculated_max", allocations[i].MaxRunners,
+				"new_max", maxRunners)
+			allocations[i].MaxRunners = maxRunners
+		}
+	}
+
+	return allocations, nil
+}
+
+// fairShares returns the share of their node pool's capacity the runner sets are entitled to.
+// Shares are weighted by priority, except for max-min fairness, which ignores priorities.
+func (r *Reconciler) fairShares(cfg *config.Config, runnerSets []*RunnerSetResources) map[types.NamespacedName]float64 {
+	weight := priorityWeight
+	if cfg.AllocationStrategy == config.AllocationStrategyMaxMinFairness {
+		weight = func(*RunnerSetResources) float64 { return 1 }
+	}
+
+	totalWeight := 0.0
+	for _, rs := range runnerSets {
+		totalWeight += weight(rs)
+	}
+
+	shares := make(map[types.NamespacedName]float64, len(runnerSets))
+	for _, rs := range runnerSets {
+		shares[rs.Key()] = weight(rs) / totalWeight
+	}
+	return shares
+}
+
 // listRunnerSets lists all AutoscalingRunnerSets in the configured namespaces
-func (r *Reconciler) listRunnerSets(ctx context.Context) ([]actionsv1alpha1.AutoscalingRunnerSet, error) {
+func (r *Reconciler) listRunnerSets(ctx context.Context, cfg *config.Config) ([]actionsv1alpha1.AutoscalingRunnerSet, error) {
 	runnerSetList := &actionsv1alpha1.AutoscalingRunnerSetList{}
 
 	// If no namespaces configured, list from all namespaces
-	if len(r.config.Namespaces) == 0 {
+	if len(cfg.Namespaces) == 0 {
 		if err := r.client.List(ctx, runnerSetList); err != nil {
 			return nil, fmt.Errorf("failed to list AutoscalingRunnerSets: %w", err)
 		}
@@ -242,7 +554,7 @@ func (r *Reconciler) listRunnerSets(ctx context.Context) ([]actionsv1alpha1.Auto
 
 	// Otherwise, list from each configured namespace
 	allRunnerSets := []actionsv1alpha1.AutoscalingRunnerSet{}
-	for _, namespace := range r.config.Namespaces {
+	for _, namespace := range cfg.Namespaces {
 		listOpts := []client.ListOption{client.InNamespace(namespace)}
 		if err := r.client.List(ctx, runnerSetList, listOpts...); err != nil {
 			r.logger.Warn("failed to list runner sets in namespace",
@@ -256,11 +568,110 @@ func (r *Reconciler) listRunnerSets(ctx context.Context) ([]actionsv1alpha1.Auto
 	return allRunnerSets, nil
 }
 
-// updateRunnerSet updates the maxRunners value for a runner set
-func (r *Reconciler) updateRunnerSet(ctx context.Context, runnerSet *actionsv1alpha1.AutoscalingRunnerSet, newMaxRunners int) error {
+// listEphemeralRunnerSets lists all EphemeralRunnerSets in the configured namespaces
+func (r *Reconciler) listEphemeralRunnerSets(ctx context.Context, cfg *config.Config) ([]actionsv1alpha1.EphemeralRunnerSet, error) {
+	ephemeralRunnerSetList := &actionsv1alpha1.EphemeralRunnerSetList{}
+
+	// If no namespaces configured, list from all namespaces
+	if len(cfg.Namespaces) == 0 {
+		if err := r.client.List(ctx, ephemeralRunnerSetList); err != nil {
+			return nil, fmt.Errorf("failed to list EphemeralRunnerSets: %w", err)
+		}
+		return ephemeralRunnerSetList.Items, nil
+	}
+
+	// Otherwise, list from each configured namespace
+	allEphemeralRunnerSets := []actionsv1alpha1.EphemeralRunnerSet{}
+	for _, namespace := range cfg.Namespaces {
+		listOpts := []client.ListOption{client.InNamespace(namespace)}
+		if err := r.client.List(ctx, ephemeralRunnerSetList, listOpts...); err != nil {
+			r.logger.Warn("failed to list ephemeral runner sets in namespace",
+				"namespace", namespace,
+				"error", err)
+			continue
+		}
+		allEphemeralRunnerSets = append(allEphemeralRunnerSets, ephemeralRunnerSetList.Items...)
+	}
+
+	return allEphemeralRunnerSets, nil
+}
+
+// applyDemandLimits collects the demand of the enabled runner sets and limits their maxRunners to it
+func (r *Reconciler) applyDemandLimits(ctx context.Context, cfg *config.Config, runnerSets []actionsv1alpha1.AutoscalingRunnerSet, enabledRunnerSets []*RunnerSetResources) {
+	// The runners requested for assigned jobs are only available from the EphemeralRunnerSets.
+	// Without them, demand is still derived from the runner set status.
+	desiredByOwner := map[types.UID]int{}
+	ephemeralRunnerSets, err := r.listEphemeralRunnerSets(ctx, cfg)
+	if err != nil {
+		r.logger.Warn("failed to list ephemeral runner sets, using runner set status only", "error", err)
+	} else {
+		desiredByOwner = desiredRunnersByOwner(ephemeralRunnerSets)
+	}
+
+	uids := make(map[types.NamespacedName]types.UID, len(runnerSets))
+	for i := range runnerSets {
+		uids[client.ObjectKeyFromObject(&runnerSets[i])] = runnerSets[i].UID
+	}
+
+	for _, rs := range enabledRunnerSets {
+		rs.DesiredRunners = desiredByOwner[uids[rs.Key()]]
+		rs.DemandMax = demandLimit(rs)
+
+		demandMax := -1
+		if rs.DemandMax != nil {
+			demandMax = *rs.DemandMax
+		}
+		r.logger.Debug("runner set demand",
+			"namespace", rs.Namespace,
+			"name", rs.Name,
+			"current_runners", rs.CurrentRunners,
+			"pending_runners", rs.PendingRunners,
+			"running_runners", rs.RunningRunners,
+			"desired_runners", rs.DesiredRunners,
+			"current_max", rs.CurrentMax,
+			"demand_max", demandMax)
+	}
+}
+
+// recordConfiguredMax persists the operator-declared maxRunners cap as an annotation
+func (r *Reconciler) recordConfiguredMax(ctx context.Context, cfg *config.Config, runnerSet *actionsv1alpha1.AutoscalingRunnerSet, configuredMax int) error {
+	if cfg.DryRun {
+		r.logger.Warn("[DRY-RUN] would record configured maxRunners",
+			"namespace", runnerSet.Namespace,
+			"name", runnerSet.Name,
+			"annotation", config.AnnotationMaxRunners,
+			"configured_max", configuredMax)
+		return nil
+	}
+
+	// Create a copy to modify
+	updated := runnerSet.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[config.AnnotationMaxRunners] = strconv.Itoa(configuredMax)
+
+	// Patch the resource
+	if err := r.client.Patch(ctx, updated, client.MergeFrom(runnerSet)); err != nil {
+		return fmt.Errorf("failed to patch AutoscalingRunnerSet: %w", err)
+	}
+
+	r.logger.Info("recorded configured maxRunners",
+		"namespace", runnerSet.Namespace,
+		"name", runnerSet.Name,
+		"configured_max", configuredMax)
+
+	return nil
+}
+
+// updateRunnerSet updates the maxRunners value and the status annotation for a runner set
+func (r *Reconciler) updateRunnerSet(ctx context.Context, runnerSet *actionsv1alpha1.AutoscalingRunnerSet, newMaxRunners int, status *RunnerSetStatus) error {
 	// Create a copy to modify
 	updated := runnerSet.DeepCopy()
 	updated.Spec.MaxRunners = &newMaxRunners
+	if err := setStatusAnnotation(updated, status); err != nil {
+		return err
+	}
 
 	// Patch the resource
 	if err := r.client.Patch(ctx, updated, client.MergeFrom(runnerSet)); err != nil {
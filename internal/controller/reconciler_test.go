@@ -0,0 +1,628 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestReconciler_ReconcileOnce_ConfiguredMaxSurvivesScaleDown(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	busyPod := makePod("busy", "node1", "6000m", "4Gi", corev1.PodRunning)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node, &busyPod, runnerSet)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	// First cycle: the cluster is busy, so maxRunners is scaled down
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	got := getRunnerSet(t, k8sClient, "default", "ci")
+	if got.Annotations[config.AnnotationMaxRunners] != "10" {
+		t.Errorf("max-runners annotation = %q, want %q", got.Annotations[config.AnnotationMaxRunners], "10")
+	}
+	if *got.Spec.MaxRunners != 1 {
+		t.Errorf("maxRunners after busy cycle = %d, want 1", *got.Spec.MaxRunners) // (10 - 6) * 0.9 = 3.6 CPUs
+	}
+
+	// Second cycle: the busy pod is gone, so maxRunners must scale back above the patched value
+	if err := k8sClient.Delete(context.Background(), &busyPod); err != nil {
+		t.Fatalf("failed to delete pod: %v", err)
+	}
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	got = getRunnerSet(t, k8sClient, "default", "ci")
+	if *got.Spec.MaxRunners != 4 {
+		t.Errorf("maxRunners after idle cycle = %d, want 4", *got.Spec.MaxRunners) // 10 * 0.9 = 9 CPUs
+	}
+}
+
+func TestReconciler_ReconcileOnce_DryRunDoesNotRecordConfiguredMax(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node, runnerSet)
+	cfg := config.DefaultConfig()
+	cfg.DryRun = true
+	reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	got := getRunnerSet(t, k8sClient, "default", "ci")
+	if _, ok := got.Annotations[config.AnnotationMaxRunners]; ok {
+		t.Error("max-runners annotation recorded in dry-run mode")
+	}
+	if *got.Spec.MaxRunners != 10 {
+		t.Errorf("maxRunners = %d, want 10 (unchanged)", *got.Spec.MaxRunners)
+	}
+}
+
+func TestReconciler_ReconcileOnce_SameNameInDifferentNamespaces(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	teamA := makeRunnerSet("team-a", "ci-default", 2, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "1000m",
+		config.AnnotationMemory:  "1Gi",
+	})
+	teamB := makeRunnerSet("team-b", "ci-default", 20, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "1000m",
+		config.AnnotationMemory:  "1Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node, teamA, teamB)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	// 9 CPUs available: each gets 4 from fair share, team-a is capped at 2 and team-b gets the rest
+	if got := getRunnerSet(t, k8sClient, "team-a", "ci-default"); *got.Spec.MaxRunners != 2 {
+		t.Errorf("team-a maxRunners = %d, want 2", *got.Spec.MaxRunners)
+	}
+	if got := getRunnerSet(t, k8sClient, "team-b", "ci-default"); *got.Spec.MaxRunners != 7 {
+		t.Errorf("team-b maxRunners = %d, want 7", *got.Spec.MaxRunners)
+	}
+}
+
+func TestReconciler_ReconcileOnce_NodeCapacityMode(t *testing.T) {
+	// Four nodes with 2 free CPUs each: 7.2 CPUs summed, but no node fits a 4 CPU runner
+	nodes := []corev1.Node{
+		makeNode("node1", "2000m", "16Gi", corev1.ConditionTrue),
+		makeNode("node2", "2000m", "16Gi", corev1.ConditionTrue),
+		makeNode("node3", "2000m", "16Gi", corev1.ConditionTrue),
+		makeNode("node4", "2000m", "16Gi", corev1.ConditionTrue),
+	}
+	runnerSet := makeRunnerSet("default", "xl", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "4000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	tests := []struct {
+		name         string
+		capacityMode string
+		want         int
+	}{
+		{
+			name:         "cluster mode sums capacity across nodes",
+			capacityMode: config.CapacityModeCluster,
+			want:         1, // 7.2 CPUs / 4 CPUs
+		},
+		{
+			name:         "node mode only counts runners that fit on a node",
+			capacityMode: config.CapacityModeNode,
+			want:         0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := newFakeClient(t, &nodes[0], &nodes[1], &nodes[2], &nodes[3], runnerSet.DeepCopy())
+			cfg := config.DefaultConfig()
+			cfg.CapacityMode = tt.capacityMode
+			reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+			if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+				t.Fatalf("ReconcileOnce() error = %v", err)
+			}
+
+			if got := getRunnerSet(t, k8sClient, "default", "xl"); *got.Spec.MaxRunners != tt.want {
+				t.Errorf("maxRunners = %d, want %d", *got.Spec.MaxRunners, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconciler_ReconcileOnce_SeparateNodePools(t *testing.T) {
+	defaultNode := makeNode("default-1", "8000m", "32Gi", corev1.ConditionTrue)
+	defaultNode.Labels = map[string]string{"pool": "default"}
+	xlNode := makeNode("xl-1", "8000m", "32Gi", corev1.ConditionTrue)
+	xlNode.Labels = map[string]string{"pool": "xl"}
+	xlNode.Spec.Taints = []corev1.Taint{{Key: "pool", Value: "xl", Effect: corev1.TaintEffectNoSchedule}}
+
+	xl := makeRunnerSet("default", "xl", 10, map[string]string{
+		config.AnnotationEnabled:  "true",
+		config.AnnotationCPU:      "4000m",
+		config.AnnotationMemory:   "4Gi",
+		config.AnnotationPriority: "100",
+	})
+	xl.Spec.Template.Spec.NodeSelector = map[string]string{"pool": "xl"}
+	xl.Spec.Template.Spec.Tolerations = []corev1.Toleration{
+		{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "xl", Effect: corev1.TaintEffectNoSchedule},
+	}
+	small := makeRunnerSet("default", "small", 10, map[string]string{
+		config.AnnotationEnabled:  "true",
+		config.AnnotationCPU:      "1000m",
+		config.AnnotationMemory:   "1Gi",
+		config.AnnotationPriority: "1",
+	})
+
+	k8sClient := newFakeClient(t, &defaultNode, &xlNode, xl, small)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	// Each runner set only gets the capacity of its own node pool (7.2 CPUs each),
+	// even though xl has a much higher priority
+	if got := getRunnerSet(t, k8sClient, "default", "xl"); *got.Spec.MaxRunners != 1 {
+		t.Errorf("xl maxRunners = %d, want 1", *got.Spec.MaxRunners)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "small"); *got.Spec.MaxRunners != 7 {
+		t.Errorf("small maxRunners = %d, want 7", *got.Spec.MaxRunners)
+	}
+}
+
+func TestReconciler_ReconcileOnce_ClusterReserve(t *testing.T) {
+	// Two nodes with 10 free CPUs each and 8 CPUs reserved across the cluster: 12 CPUs for runners
+	node1 := makeNode("node1", "10000m", "40Gi", corev1.ConditionTrue)
+	node1.Labels = map[string]string{"pool": "ci"}
+	node2 := makeNode("node2", "10000m", "40Gi", corev1.ConditionTrue)
+
+	tests := []struct {
+		name         string
+		capacityMode string
+		nodeSelector map[string]string
+		want         int
+	}{
+		{
+			name:         "cluster mode",
+			capacityMode: config.CapacityModeCluster,
+			want:         12,
+		},
+		{
+			name:         "node mode",
+			capacityMode: config.CapacityModeNode,
+			want:         12,
+		},
+		{
+			name:         "node pool spanning some nodes",
+			capacityMode: config.CapacityModeCluster,
+			nodeSelector: map[string]string{"pool": "ci"},
+			want:         6, // node1 keeps half of the reserve free
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runnerSet := makeRunnerSet("default", "ci", 100, map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "1000m",
+				config.AnnotationMemory:  "1Gi",
+			})
+			runnerSet.Spec.Template.Spec.NodeSelector = tt.nodeSelector
+
+			k8sClient := newFakeClient(t, &node1, &node2, runnerSet)
+			cfg := config.DefaultConfig()
+			cfg.CapacityMode = tt.capacityMode
+			cfg.CPUBufferPercent = 0
+			cfg.MemoryBufferPercent = 0
+			cfg.CPUReserve = resource.MustParse("8")
+			reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+			if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+				t.Fatalf("ReconcileOnce() error = %v", err)
+			}
+
+			if got := getRunnerSet(t, k8sClient, "default", "ci"); *got.Spec.MaxRunners != tt.want {
+				t.Errorf("maxRunners = %d, want %d", *got.Spec.MaxRunners, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconciler_ReconcileOnce_ReserveUnscheduledPods(t *testing.T) {
+	// Two nodes with 10 free CPUs and 2 GPUs each. A pending pod waits for 8 CPUs and 2 GPUs.
+	node1 := makeNode("node1", "10000m", "40Gi", corev1.ConditionTrue)
+	node1.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("2")
+	node2 := makeNode("node2", "10000m", "40Gi", corev1.ConditionTrue)
+	node2.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("2")
+	pending := makePod("training", "", "8000m", "4Gi", corev1.PodPending)
+	pending.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("2")
+
+	tests := []struct {
+		name              string
+		capacityMode      string
+		extendedResources string
+		want              int
+	}{
+		{
+			name:         "cluster mode keeps CPU free",
+			capacityMode: config.CapacityModeCluster,
+			want:         12,
+		},
+		{
+			name:         "node mode keeps CPU free",
+			capacityMode: config.CapacityModeNode,
+			want:         12,
+		},
+		{
+			name:              "cluster mode keeps GPUs free",
+			capacityMode:      config.CapacityModeCluster,
+			extendedResources: "nvidia.com/gpu=1",
+			want:              2,
+		},
+		{
+			name:              "node mode keeps GPUs free",
+			capacityMode:      config.CapacityModeNode,
+			extendedResources: "nvidia.com/gpu=1",
+			want:              2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "1000m",
+				config.AnnotationMemory:  "1Gi",
+			}
+			if tt.extendedResources != "" {
+				annotations[config.AnnotationExtendedResources] = tt.extendedResources
+			}
+			runnerSet := makeRunnerSet("default", "ci", 100, annotations)
+
+			k8sClient := newFakeClient(t, &node1, &node2, &pending, runnerSet)
+			cfg := config.DefaultConfig()
+			cfg.CapacityMode = tt.capacityMode
+			cfg.CPUBufferPercent = 0
+			cfg.MemoryBufferPercent = 0
+			cfg.ReserveUnscheduledPods = true
+			reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+			if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+				t.Fatalf("ReconcileOnce() error = %v", err)
+			}
+
+			if got := getRunnerSet(t, k8sClient, "default", "ci"); *got.Spec.MaxRunners != tt.want {
+				t.Errorf("maxRunners = %d, want %d", *got.Spec.MaxRunners, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconciler_ReconcileOnce_ExcludesCordonedNodes(t *testing.T) {
+	node1 := makeNode("node1", "10000m", "40Gi", corev1.ConditionTrue)
+	node2 := makeNode("node2", "10000m", "40Gi", corev1.ConditionTrue)
+	node2.Spec.Unschedulable = true
+	runnerSet := makeRunnerSet("default", "ci", 20, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node1, &node2, runnerSet)
+	cfg := config.DefaultConfig()
+	reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	// The cordoned node is dropped: 10 cores - 10% buffer = 9 cores = 4 runners
+	if got := getRunnerSet(t, k8sClient, "default", "ci"); *got.Spec.MaxRunners != 4 {
+		t.Errorf("maxRunners = %d, want 4", *got.Spec.MaxRunners)
+	}
+
+	// Counting cordoned nodes again takes effect on the next reconciliation
+	updated := config.DefaultConfig()
+	updated.NodeFilter.ExcludeCordoned = false
+	reconciler.UpdateConfig(updated)
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "ci"); *got.Spec.MaxRunners != 9 {
+		t.Errorf("maxRunners with cordoned nodes = %d, want 9", *got.Spec.MaxRunners)
+	}
+}
+
+func TestReconciler_ReconcileOnce_PodLimit(t *testing.T) {
+	node := makeNode("node1", "32000m", "128Gi", corev1.ConditionTrue)
+	node.Status.Allocatable[corev1.ResourcePods] = resource.MustParse("10")
+	daemon := makePod("daemon", "node1", "100m", "128Mi", corev1.PodRunning)
+	app := makePod("app", "node1", "100m", "128Mi", corev1.PodRunning)
+
+	// In kubernetes container mode, every job runs in a workflow pod next to the runner pod
+	containerMode := makeRunnerSet("default", "container-mode", 10, map[string]string{
+		config.AnnotationEnabled:       "true",
+		config.AnnotationCPU:           "1000m",
+		config.AnnotationMemory:        "1Gi",
+		config.AnnotationPodsPerRunner: "2",
+	})
+
+	k8sClient := newFakeClient(t, &node, &daemon, &app, containerMode)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	// Plenty of CPU and memory is free, but only 8 pods for runners taking 2 pods each
+	if got := getRunnerSet(t, k8sClient, "default", "container-mode"); *got.Spec.MaxRunners != 4 {
+		t.Errorf("container-mode maxRunners = %d, want 4", *got.Spec.MaxRunners)
+	}
+}
+
+func TestReconciler_ReconcileOnce_ExtendedResources(t *testing.T) {
+	gpuNode := makeNode("gpu-1", "8000m", "32Gi", corev1.ConditionTrue)
+	gpuNode.Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("2")
+	cpuNode := makeNode("cpu-1", "8000m", "32Gi", corev1.ConditionTrue)
+	trainingPod := makePod("training", "gpu-1", "1000m", "4Gi", corev1.PodRunning)
+	trainingPod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+
+	ml := makeRunnerSet("default", "ml", 10, map[string]string{
+		config.AnnotationEnabled:           "true",
+		config.AnnotationCPU:               "1000m",
+		config.AnnotationMemory:            "1Gi",
+		config.AnnotationExtendedResources: "nvidia.com/gpu=1",
+		config.AnnotationPriority:          "100",
+	})
+	build := makeRunnerSet("default", "build", 5, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "1000m",
+		config.AnnotationMemory:  "1Gi",
+	})
+
+	k8sClient := newFakeClient(t, &gpuNode, &cpuNode, &trainingPod, ml, build)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	// Plenty of CPU and memory is free, but only one GPU
+	if got := getRunnerSet(t, k8sClient, "default", "ml"); *got.Spec.MaxRunners != 1 {
+		t.Errorf("ml maxRunners = %d, want 1", *got.Spec.MaxRunners)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "build"); *got.Spec.MaxRunners != 5 {
+		t.Errorf("build maxRunners = %d, want 5", *got.Spec.MaxRunners)
+	}
+}
+
+func TestReconciler_ReconcileOnce_AllocationStrategy(t *testing.T) {
+	node := makeNode("node1", "10000m", "40Gi", corev1.ConditionTrue)
+	high := makeRunnerSet("default", "high", 20, map[string]string{
+		config.AnnotationEnabled:  "true",
+		config.AnnotationCPU:      "1000m",
+		config.AnnotationMemory:   "1Gi",
+		config.AnnotationPriority: "9",
+	})
+	low := makeRunnerSet("default", "low", 20, map[string]string{
+		config.AnnotationEnabled:  "true",
+		config.AnnotationCPU:      "1000m",
+		config.AnnotationMemory:   "1Gi",
+		config.AnnotationPriority: "1",
+	})
+
+	k8sClient := newFakeClient(t, &node, high, low)
+	cfg := config.DefaultConfig()
+	cfg.AllocationStrategy = config.AllocationStrategyStrictPriority
+	reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	// Strict priority gives all 9 CPUs (10 * 0.9) to the high priority runner set
+	if got := getRunnerSet(t, k8sClient, "default", "high"); *got.Spec.MaxRunners != 9 {
+		t.Errorf("high maxRunners = %d, want 9", *got.Spec.MaxRunners)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "low"); *got.Spec.MaxRunners != 0 {
+		t.Errorf("low maxRunners = %d, want 0", *got.Spec.MaxRunners)
+	}
+
+	// An unknown strategy fails the reconciliation instead of silently falling back
+	cfg.AllocationStrategy = "unknown"
+	if err := reconciler.ReconcileOnce(context.Background()); err == nil {
+		t.Error("ReconcileOnce() with unknown allocation strategy error = nil, want error")
+	}
+}
+
+func TestReconciler_ReconcileOnce_DemandAware(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	annotations := func(priority string) map[string]string {
+		return map[string]string{
+			config.AnnotationEnabled:    "true",
+			config.AnnotationCPU:        "1000m",
+			config.AnnotationMemory:     "1Gi",
+			config.AnnotationPriority:   priority,
+			config.AnnotationMaxRunners: "20",
+		}
+	}
+
+	// The idle runner set has a much higher priority, but no runners
+	idle := makeRunnerSet("default", "idle", 5, annotations("9"))
+	busy := makeRunnerSet("default", "busy", 1, annotations("1"))
+	busy.UID = "busy-uid"
+	busy.Status.CurrentRunners = 1
+
+	// The listener requests 1 runner for assigned jobs, which is all the current maxRunners allows
+	controller := true
+	ephemeralRunnerSet := &actionsv1alpha1.EphemeralRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "busy-abcde",
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "actions.github.com/v1alpha1", Kind: "AutoscalingRunnerSet", Name: "busy", UID: "busy-uid", Controller: &controller},
+			},
+		},
+		Spec: actionsv1alpha1.EphemeralRunnerSetSpec{Replicas: 1},
+	}
+
+	k8sClient := newFakeClient(t, &node, idle, busy, ephemeralRunnerSet)
+	cfg := config.DefaultConfig()
+	cfg.DemandAware = true
+	reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	// The idle runner set keeps headroom for one job, the saturated one gets the remaining 8 of 9 CPUs (10 * 0.9)
+	if got := getRunnerSet(t, k8sClient, "default", "idle"); *got.Spec.MaxRunners != 1 {
+		t.Errorf("idle maxRunners = %d, want 1", *got.Spec.MaxRunners)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "busy"); *got.Spec.MaxRunners != 8 {
+		t.Errorf("busy maxRunners = %d, want 8", *got.Spec.MaxRunners)
+	}
+
+	// Once the busy runner set has headroom, it is limited to its demand plus one runner
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "busy"); *got.Spec.MaxRunners != 2 {
+		t.Errorf("busy maxRunners after second cycle = %d, want 2", *got.Spec.MaxRunners)
+	}
+}
+
+func TestReconciler_NeedLeaderElection(t *testing.T) {
+	cfg := config.DefaultConfig()
+	reconciler := NewReconciler(newFakeClient(t), testLogger(), cfg)
+	if !reconciler.NeedLeaderElection() {
+		t.Error("NeedLeaderElection() = false, want true")
+	}
+
+	// Dry-run replicas observe alongside the leader
+	cfg.DryRun = true
+	if reconciler.NeedLeaderElection() {
+		t.Error("NeedLeaderElection() in dry-run mode = true, want false")
+	}
+}
+
+func TestReconciler_UpdateConfig(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+	otherRunnerSet := makeRunnerSet("other", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node, runnerSet, otherRunnerSet)
+	cfg := config.DefaultConfig()
+	cfg.Namespaces = []string{"default"}
+	reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "ci"); *got.Spec.MaxRunners != 4 {
+		t.Errorf("maxRunners = %d, want 4", *got.Spec.MaxRunners) // 10 * 0.9 = 9 CPUs
+	}
+
+	// A larger buffer and another namespace take effect with the next reconciliation
+	updated := config.DefaultConfig()
+	updated.CPUBufferPercent = 50
+	updated.Namespaces = []string{"other"}
+	reconciler.UpdateConfig(updated)
+
+	if len(reconciler.triggers) != 1 {
+		t.Error("UpdateConfig() didn't trigger a reconciliation")
+	}
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := getRunnerSet(t, k8sClient, "other", "ci"); *got.Spec.MaxRunners != 2 {
+		t.Errorf("maxRunners in new namespace = %d, want 2", *got.Spec.MaxRunners) // 10 * 0.5 = 5 CPUs
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "ci"); *got.Spec.MaxRunners != 4 {
+		t.Errorf("maxRunners in old namespace = %d, want unchanged 4", *got.Spec.MaxRunners)
+	}
+}
+
+// Helper functions
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	if err := actionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register AutoscalingRunnerSet scheme: %v", err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		Build()
+}
+
+func makeRunnerSet(namespace, name string, maxRunners int, annotations map[string]string) *actionsv1alpha1.AutoscalingRunnerSet {
+	return &actionsv1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: annotations,
+		},
+		Spec: actionsv1alpha1.AutoscalingRunnerSetSpec{
+			MaxRunners: intPtr(maxRunners),
+		},
+	}
+}
+
+func getRunnerSet(t *testing.T, k8sClient client.Client, namespace, name string) *actionsv1alpha1.AutoscalingRunnerSet {
+	t.Helper()
+
+	runnerSet := &actionsv1alpha1.AutoscalingRunnerSet{}
+	if err := k8sClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, runnerSet); err != nil {
+		t.Fatalf("failed to get runner set %s/%s: %v", namespace, name, err)
+	}
+	return runnerSet
+}
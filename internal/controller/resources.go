@@ -1,24 +1,120 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strconv"
+	"strings"
 
 	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
 )
 
+// ErrAutoscalingNotEnabled is returned for runner sets that didn't opt in to autoscaling
+var ErrAutoscalingNotEnabled = errors.New("autoscaling not enabled")
+
 // RunnerSetResources contains the resource requirements for a runner set
 type RunnerSetResources struct {
+	Namespace     string
 	Name          string
 	CPUMillis     int64
 	MemoryBytes   int64
 	Priority      int
 	MinRunners    int // Minimum guaranteed maxRunners (doesn't keep pods running)
 	CurrentMax    int
-	ConfiguredMax int // From original spec, used as cap
+	ConfiguredMax int // Operator-declared ceiling, used as cap (0 means no cap)
+
+	// ScalarResources are the ephemeral storage, extended resources and hugepages requested by a runner,
+	// e.g. nvidia.com/gpu, and the pods it takes on a node
+	ScalarResources ScalarResources
+
+	// Scheduling constraints of the runner pod template, restricting the nodes runners can use
+	NodeSelector map[string]string
+	Affinity     *corev1.Affinity
+	Tolerations  []corev1.Toleration
+
+	// Demand signals, used by demand-aware allocation
+	CurrentRunners int  // Runners currently managed by the runner set (status.currentRunners)
+	PendingRunners int  // Ephemeral runners waiting to start
+	RunningRunners int  // Ephemeral runners executing jobs
+	DesiredRunners int  // Runners requested by the listener for assigned jobs (EphemeralRunnerSet spec.replicas)
+	DemandMax      *int // Limit derived from demand (nil means no limit)
+}
+
+// ScalarResources are amounts of resources other than CPU and memory by resource name, in the resource's
+// base unit: bytes for ephemeral storage and hugepages, devices for extended resources like nvidia.com/gpu,
+// and pods for the pod count
+type ScalarResources map[corev1.ResourceName]int64
+
+// newScalarResources returns the scalar resources of a resource list, ignoring CPU and memory
+func newScalarResources(list corev1.ResourceList) ScalarResources {
+	resources := ScalarResources{}
+	for name, quantity := range list {
+		if isScalarResource(name) {
+			resources[name] = quantity.Value()
+		}
+	}
+	return resources
+}
+
+// isScalarResource reports whether a resource is tracked as a scalar resource: the pod count, ephemeral storage,
+// hugepages and extended resources
+func isScalarResource(name corev1.ResourceName) bool {
+	return name == corev1.ResourcePods || name == corev1.ResourceEphemeralStorage || isExtendedResource(name)
+}
+
+// isExtendedResource reports whether a resource can be set with the extended-resources annotation: hugepages and
+// extended resources, which are all resources with a domain outside kubernetes.io
+func isExtendedResource(name corev1.ResourceName) bool {
+	if strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) {
+		return true
+	}
+	return strings.Contains(string(name), "/") && !strings.Contains(string(name), "kubernetes.io/")
+}
+
+// clone returns a copy that can be changed without changing the original
+func (s ScalarResources) clone() ScalarResources {
+	clone := make(ScalarResources, len(s))
+	maps.Copy(clone, s)
+	return clone
+}
+
+// add adds n times the given resources
+func (s ScalarResources) add(resources ScalarResources, n int64) {
+	for name, amount := range resources {
+		s[name] += n * amount
+	}
+}
+
+// names returns the resource names in sorted order
+func (s ScalarResources) names() []corev1.ResourceName {
+	return slices.Sorted(maps.Keys(s))
+}
+
+// Key returns the namespaced name identifying the runner set
+func (r *RunnerSetResources) Key() types.NamespacedName {
+	return types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+}
+
+// maxRunnersCap returns the most runners the runner set can be allocated, or -1 if there is no cap.
+// It combines the configured max with the demand limit.
+func (r *RunnerSetResources) maxRunnersCap() int {
+	limit := -1
+	if r.ConfiguredMax > 0 {
+		limit = r.ConfiguredMax
+	}
+	if r.DemandMax != nil && (limit == -1 || *r.DemandMax < limit) {
+		limit = max(*r.DemandMax, 0)
+	}
+	return limit
 }
 
 // ExtractRunnerSetResources extracts resource requirements from a runner set
@@ -26,20 +122,35 @@ type RunnerSetResources struct {
 func ExtractRunnerSetResources(rs *actionsv1alpha1.AutoscalingRunnerSet) (*RunnerSetResources, error) {
 	// Check if autoscaling is enabled via annotation (opt-in)
 	if rs.Annotations[config.AnnotationEnabled] != "true" {
-		return nil, fmt.Errorf("autoscaling not enabled (missing or false: %s)", config.AnnotationEnabled)
+		return nil, fmt.Errorf("%w (missing or false: %s)", ErrAutoscalingNotEnabled, config.AnnotationEnabled)
 	}
 
 	resources := &RunnerSetResources{
-		Name:     rs.Name,
-		Priority: 0, // Default priority
+		Namespace:    rs.Namespace,
+		Name:         rs.Name,
+		Priority:     0, // Default priority
+		NodeSelector: rs.Spec.Template.Spec.NodeSelector,
+		Affinity:     rs.Spec.Template.Spec.Affinity,
+		Tolerations:  rs.Spec.Template.Spec.Tolerations,
+
+		CurrentRunners: rs.Status.CurrentRunners,
+		PendingRunners: rs.Status.PendingEphemeralRunners,
+		RunningRunners: rs.Status.RunningEphemeralRunners,
 	}
 
 	// Get current maxRunners
 	if rs.Spec.MaxRunners != nil {
 		resources.CurrentMax = *rs.Spec.MaxRunners
-		resources.ConfiguredMax = *rs.Spec.MaxRunners // Use as cap
 	}
 
+	// Get the operator-declared cap, which is not necessarily the current spec value
+	// because the controller overwrites spec.maxRunners on every reconciliation
+	configuredMax, err := extractConfiguredMax(rs)
+	if err != nil {
+		return nil, err
+	}
+	resources.ConfiguredMax = configuredMax
+
 	// Extract priority from annotation
 	if priorityStr, ok := rs.Annotations[config.AnnotationPriority]; ok {
 		priority, err := strconv.Atoi(priorityStr)
@@ -58,15 +169,28 @@ func ExtractRunnerSetResources(rs *actionsv1alpha1.AutoscalingRunnerSet) (*Runne
 		if minRunners < 0 {
 			return nil, fmt.Errorf("min-runners must be non-negative, got %d", minRunners)
 		}
+		// The configured max would silently win over the minimum
+		if configuredMax > 0 && minRunners > configuredMax {
+			return nil, fmt.Errorf("min-runners %d exceeds the configured max of %d runners", minRunners, configuredMax)
+		}
 		resources.MinRunners = minRunners
 	}
 
+	// The multiplier only scales footprints derived from the pod template, annotations are the full footprint
+	multiplier, err := extractPodMultiplier(rs)
+	if err != nil {
+		return nil, err
+	}
+
 	// Try to get CPU from annotation first
 	if cpuStr, ok := rs.Annotations[config.AnnotationCPU]; ok {
 		cpu, err := parseResourceQuantityOrInt(cpuStr, true)
 		if err != nil {
 			return nil, fmt.Errorf("invalid CPU annotation: %w", err)
 		}
+		if cpu <= 0 {
+			return nil, fmt.Errorf("CPU annotation must be positive, got %s", cpuStr)
+		}
 		resources.CPUMillis = cpu
 	} else {
 		// Fall back to pod template spec
@@ -74,7 +198,7 @@ func ExtractRunnerSetResources(rs *actionsv1alpha1.AutoscalingRunnerSet) (*Runne
 		if err != nil {
 			return nil, fmt.Errorf("CPU not specified in annotation or pod spec: %w", err)
 		}
-		resources.CPUMillis = cpu
+		resources.CPUMillis = scaleFootprint(cpu, multiplier)
 	}
 
 	// Try to get memory from annotation first
@@ -83,6 +207,9 @@ func ExtractRunnerSetResources(rs *actionsv1alpha1.AutoscalingRunnerSet) (*Runne
 		if err != nil {
 			return nil, fmt.Errorf("invalid memory annotation: %w", err)
 		}
+		if mem <= 0 {
+			return nil, fmt.Errorf("memory annotation must be positive, got %s", memStr)
+		}
 		resources.MemoryBytes = mem
 	} else {
 		// Fall back to pod template spec
@@ -90,38 +217,215 @@ func ExtractRunnerSetResources(rs *actionsv1alpha1.AutoscalingRunnerSet) (*Runne
 		if err != nil {
 			return nil, fmt.Errorf("memory not specified in annotation or pod spec: %w", err)
 		}
-		resources.MemoryBytes = mem
+		resources.MemoryBytes = scaleFootprint(mem, multiplier)
+	}
+
+	// Extended resources from the annotation, falling back to the pod template
+	templateRequests := templatePodRequests(&rs.Spec.Template.Spec)
+	for name, quantity := range templateRequests {
+		if err := checkWholeQuantity(name, quantity); err != nil {
+			return nil, fmt.Errorf("invalid pod template resources: %w", err)
+		}
+	}
+	templateScalar := newScalarResources(templateRequests)
+	for name, amount := range templateScalar {
+		templateScalar[name] = scaleFootprint(amount, multiplier)
+	}
+	if extendedStr, ok := rs.Annotations[config.AnnotationExtendedResources]; ok {
+		extended, err := parseExtendedResources(extendedStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extended-resources annotation: %w", err)
+		}
+		resources.ScalarResources = extended
+	} else {
+		resources.ScalarResources = ScalarResources{}
+		for name, amount := range templateScalar {
+			if isExtendedResource(name) {
+				resources.ScalarResources[name] = amount
+			}
+		}
+	}
+
+	// Ephemeral storage is optional, runners without storage requests in the pod template aren't limited by it
+	storage := templateScalar[corev1.ResourceEphemeralStorage]
+	if storageStr, ok := rs.Annotations[config.AnnotationEphemeralStorage]; ok {
+		storage, err = parseResourceQuantityOrInt(storageStr, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ephemeral-storage annotation: %w", err)
+		}
+		if storage <= 0 {
+			return nil, fmt.Errorf("ephemeral-storage annotation must be positive, got %s", storageStr)
+		}
+	}
+	if storage > 0 {
+		resources.ScalarResources[corev1.ResourceEphemeralStorage] = storage
+	}
+
+	// Every runner takes at least its own pod of the node's pod limit
+	podsPerRunner, err := extractPodsPerRunner(rs, multiplier)
+	if err != nil {
+		return nil, err
 	}
+	resources.ScalarResources[corev1.ResourcePods] = podsPerRunner
 
 	return resources, nil
 }
 
-// extractCPUFromPodSpec extracts CPU request from the runner container in pod template
-func extractCPUFromPodSpec(rs *actionsv1alpha1.AutoscalingRunnerSet) (int64, error) {
-	for _, container := range rs.Spec.Template.Spec.Containers {
-		if container.Name == "runner" {
-			if container.Resources.Requests != nil {
-				if cpu, ok := container.Resources.Requests["cpu"]; ok {
-					return parseCPU(cpu)
-				}
-			}
+// parseExtendedResources parses comma-separated name=quantity pairs of extended resources and hugepages,
+// e.g. "nvidia.com/gpu=1,hugepages-2Mi=512Mi"
+func parseExtendedResources(value string) (ScalarResources, error) {
+	resources := ScalarResources{}
+	for pair := range strings.SplitSeq(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		nameStr, quantityStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q must be a name=quantity pair", pair)
+		}
+		name := corev1.ResourceName(strings.TrimSpace(nameStr))
+		if !isExtendedResource(name) {
+			return nil, fmt.Errorf("%q is not an extended resource or hugepages", name)
+		}
+		quantity, err := resource.ParseQuantity(strings.TrimSpace(quantityStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity for %s: %w", name, err)
+		}
+		if quantity.Sign() < 0 {
+			return nil, fmt.Errorf("%s must not be negative, got %s", name, quantity.String())
 		}
+		if err := checkWholeQuantity(name, quantity); err != nil {
+			return nil, err
+		}
+		resources[name] = quantity.Value()
 	}
-	return 0, fmt.Errorf("no CPU request found in runner container")
+	return resources, nil
 }
 
-// extractMemoryFromPodSpec extracts memory request from the runner container in pod template
-func extractMemoryFromPodSpec(rs *actionsv1alpha1.AutoscalingRunnerSet) (int64, error) {
-	for _, container := range rs.Spec.Template.Spec.Containers {
-		if container.Name == "runner" {
-			if container.Resources.Requests != nil {
-				if mem, ok := container.Resources.Requests["memory"]; ok {
-					return parseMemory(mem)
-				}
-			}
+// checkWholeQuantity rejects fractional quantities of extended resources, which Kubernetes only accepts as whole
+// numbers. Rounding them up would silently count e.g. "0.5" GPUs as a whole one. Hugepages are bytes and may be any size.
+func checkWholeQuantity(name corev1.ResourceName, quantity resource.Quantity) error {
+	if strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix) || !isExtendedResource(name) {
+		return nil
+	}
+	if quantity.MilliValue()%1000 != 0 {
+		return fmt.Errorf("%s must be a whole number, got %s", name, quantity.String())
+	}
+	return nil
+}
+
+// extractConfiguredMax determines the operator-declared maxRunners ceiling of a runner set.
+//
+// The max-runners annotation is authoritative once it has been recorded. Runner sets adopted
+// before the annotation existed may already have a patched spec.maxRunners, so the value from
+// the kubectl last-applied-configuration is preferred over the live spec when available.
+func extractConfiguredMax(rs *actionsv1alpha1.AutoscalingRunnerSet) (int, error) {
+	if maxRunnersStr, ok := rs.Annotations[config.AnnotationMaxRunners]; ok {
+		maxRunners, err := strconv.Atoi(maxRunnersStr)
+		if err != nil {
+			return 0, fmt.Errorf("invalid max-runners annotation: %w", err)
+		}
+		if maxRunners < 0 {
+			return 0, fmt.Errorf("max-runners must be non-negative, got %d", maxRunners)
 		}
+		return maxRunners, nil
+	}
+
+	if maxRunners, ok := lastAppliedMaxRunners(rs); ok {
+		return maxRunners, nil
+	}
+
+	if rs.Spec.MaxRunners != nil {
+		return *rs.Spec.MaxRunners, nil
+	}
+	return 0, nil
+}
+
+// lastAppliedMaxRunners reads spec.maxRunners from the kubectl last-applied-configuration annotation
+func lastAppliedMaxRunners(rs *actionsv1alpha1.AutoscalingRunnerSet) (int, bool) {
+	lastApplied, ok := rs.Annotations[corev1.LastAppliedConfigAnnotation]
+	if !ok {
+		return 0, false
 	}
-	return 0, fmt.Errorf("no memory request found in runner container")
+
+	var applied struct {
+		Spec struct {
+			MaxRunners *int `json:"maxRunners"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal([]byte(lastApplied), &applied); err != nil || applied.Spec.MaxRunners == nil {
+		return 0, false
+	}
+	return *applied.Spec.MaxRunners, true
+}
+
+// extractPodMultiplier returns the multiplier of the pod template footprint, 1 if not annotated
+func extractPodMultiplier(rs *actionsv1alpha1.AutoscalingRunnerSet) (float64, error) {
+	multiplierStr, ok := rs.Annotations[config.AnnotationPodMultiplier]
+	if !ok {
+		return 1, nil
+	}
+	multiplier, err := strconv.ParseFloat(multiplierStr, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pod-multiplier annotation: %w", err)
+	}
+	if math.IsNaN(multiplier) || math.IsInf(multiplier, 0) || multiplier < 1 {
+		return 0, fmt.Errorf("pod-multiplier must be at least 1, got %s", multiplierStr)
+	}
+	return multiplier, nil
+}
+
+// extractPodsPerRunner returns the pods a runner takes on its node. Without the annotation, the pod multiplier is
+// rounded up, as it accounts for pods created next to the runner pod, e.g. "1.5" for a workflow pod half the size.
+func extractPodsPerRunner(rs *actionsv1alpha1.AutoscalingRunnerSet, multiplier float64) (int64, error) {
+	podsStr, ok := rs.Annotations[config.AnnotationPodsPerRunner]
+	if !ok {
+		return int64(math.Ceil(multiplier)), nil
+	}
+	pods, err := strconv.ParseInt(podsStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pods-per-runner annotation: %w", err)
+	}
+	if pods < 1 {
+		return 0, fmt.Errorf("pods-per-runner must be at least 1, got %d", pods)
+	}
+	return pods, nil
+}
+
+// scaleFootprint multiplies a footprint, rounding up so runners are never undercounted
+func scaleFootprint(value int64, multiplier float64) int64 {
+	if multiplier == 1 {
+		return value
+	}
+	return int64(math.Ceil(float64(value) * multiplier))
+}
+
+// extractCPUFromPodSpec extracts the CPU request of a runner pod from the pod template.
+// All containers are counted, so sidecars like dind are included, together with init containers
+// and the pod overhead, the same way the scheduler does.
+func extractCPUFromPodSpec(rs *actionsv1alpha1.AutoscalingRunnerSet) (int64, error) {
+	cpu, ok := templatePodRequests(&rs.Spec.Template.Spec)[corev1.ResourceCPU]
+	if !ok || cpu.IsZero() {
+		return 0, fmt.Errorf("no CPU request found in pod template")
+	}
+	return parseCPU(cpu)
+}
+
+// extractMemoryFromPodSpec extracts the memory request of a runner pod from the pod template.
+// All containers are counted, so sidecars like dind are included, together with init containers
+// and the pod overhead, the same way the scheduler does.
+func extractMemoryFromPodSpec(rs *actionsv1alpha1.AutoscalingRunnerSet) (int64, error) {
+	mem, ok := templatePodRequests(&rs.Spec.Template.Spec)[corev1.ResourceMemory]
+	if !ok || mem.IsZero() {
+		return 0, fmt.Errorf("no memory request found in pod template")
+	}
+	return parseMemory(mem)
+}
+
+// templatePodRequests returns the effective requests of a runner pod created from a pod template
+func templatePodRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	return podRequests(&corev1.Pod{Spec: *spec})
 }
 
 // parseCPU parses a Kubernetes CPU quantity to millicores
@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"maps"
+	"strings"
 	"testing"
 
 	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
@@ -12,6 +14,8 @@ import (
 )
 
 func TestExtractRunnerSetResources(t *testing.T) {
+	sidecarRestartPolicy := corev1.ContainerRestartPolicyAlways
+
 	tests := []struct {
 		name        string
 		runnerSet   *actionsv1alpha1.AutoscalingRunnerSet
@@ -119,6 +123,68 @@ func TestExtractRunnerSetResources(t *testing.T) {
 				ConfiguredMax: 8,
 			},
 		},
+		{
+			name: "pod template with init containers, sidecar and overhead",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled: "true",
+					},
+				},
+				Spec: actionsv1alpha1.AutoscalingRunnerSetSpec{
+					MaxRunners: intPtr(8),
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							InitContainers: []corev1.Container{
+								{
+									Name: "init-work",
+									Resources: corev1.ResourceRequirements{
+										Requests: corev1.ResourceList{
+											corev1.ResourceCPU:    resource.MustParse("3000m"),
+											corev1.ResourceMemory: resource.MustParse("1Gi"),
+										},
+									},
+								},
+								{
+									Name:          "proxy",
+									RestartPolicy: &sidecarRestartPolicy,
+									Resources: corev1.ResourceRequirements{
+										Requests: corev1.ResourceList{
+											corev1.ResourceCPU:    resource.MustParse("100m"),
+											corev1.ResourceMemory: resource.MustParse("256Mi"),
+										},
+									},
+								},
+							},
+							Containers: []corev1.Container{
+								{
+									Name: "runner",
+									Resources: corev1.ResourceRequirements{
+										Requests: corev1.ResourceList{
+											corev1.ResourceCPU:    resource.MustParse("1000m"),
+											corev1.ResourceMemory: resource.MustParse("2Gi"),
+										},
+									},
+								},
+							},
+							Overhead: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("250m"),
+								corev1.ResourceMemory: resource.MustParse("256Mi"),
+							},
+						},
+					},
+				},
+			},
+			want: &RunnerSetResources{
+				Name:          "test-runner",
+				CPUMillis:     3250,                             // init container, plus overhead
+				MemoryBytes:   (2048 + 256 + 256) * 1024 * 1024, // runner and sidecar, plus overhead
+				Priority:      0,
+				CurrentMax:    8,
+				ConfiguredMax: 8,
+			},
+		},
 		{
 			name: "default priority when not specified",
 			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
@@ -189,6 +255,66 @@ func TestExtractRunnerSetResources(t *testing.T) {
 			wantErr:     true,
 			errContains: "invalid memory annotation",
 		},
+		{
+			name: "zero CPU annotation",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled: "true",
+						config.AnnotationCPU:     "0",
+						config.AnnotationMemory:  "2Gi",
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "CPU annotation must be positive, got 0",
+		},
+		{
+			name: "negative CPU annotation",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled: "true",
+						config.AnnotationCPU:     "-2000m",
+						config.AnnotationMemory:  "2Gi",
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "CPU annotation must be positive, got -2000m",
+		},
+		{
+			name: "zero memory annotation",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled: "true",
+						config.AnnotationCPU:     "1000m",
+						config.AnnotationMemory:  "0",
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "memory annotation must be positive, got 0",
+		},
+		{
+			name: "negative memory annotation",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled: "true",
+						config.AnnotationCPU:     "1000m",
+						config.AnnotationMemory:  "-1Gi",
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "memory annotation must be positive, got -1Gi",
+		},
 		{
 			name: "missing CPU in annotation and pod spec",
 			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
@@ -247,6 +373,127 @@ func TestExtractRunnerSetResources(t *testing.T) {
 			wantErr:     true,
 			errContains: "memory not specified",
 		},
+		{
+			name: "max-runners annotation takes precedence over patched spec",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled:    "true",
+						config.AnnotationCPU:        "1000m",
+						config.AnnotationMemory:     "2Gi",
+						config.AnnotationMaxRunners: "20",
+					},
+				},
+				Spec: actionsv1alpha1.AutoscalingRunnerSetSpec{
+					MaxRunners: intPtr(2), // Patched by the controller
+				},
+			},
+			want: &RunnerSetResources{
+				Name:          "test-runner",
+				CPUMillis:     1000,
+				MemoryBytes:   2 * 1024 * 1024 * 1024,
+				CurrentMax:    2,
+				ConfiguredMax: 20,
+			},
+		},
+		{
+			name: "last-applied-configuration migrates already patched spec",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled:           "true",
+						config.AnnotationCPU:               "1000m",
+						config.AnnotationMemory:            "2Gi",
+						corev1.LastAppliedConfigAnnotation: `{"apiVersion":"actions.github.com/v1alpha1","kind":"AutoscalingRunnerSet","spec":{"maxRunners":12}}`,
+					},
+				},
+				Spec: actionsv1alpha1.AutoscalingRunnerSetSpec{
+					MaxRunners: intPtr(3), // Patched by an earlier controller version
+				},
+			},
+			want: &RunnerSetResources{
+				Name:          "test-runner",
+				CPUMillis:     1000,
+				MemoryBytes:   2 * 1024 * 1024 * 1024,
+				CurrentMax:    3,
+				ConfiguredMax: 12,
+			},
+		},
+		{
+			name: "last-applied-configuration without maxRunners falls back to spec",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled:           "true",
+						config.AnnotationCPU:               "1000m",
+						config.AnnotationMemory:            "2Gi",
+						corev1.LastAppliedConfigAnnotation: `{"spec":{}}`,
+					},
+				},
+				Spec: actionsv1alpha1.AutoscalingRunnerSetSpec{
+					MaxRunners: intPtr(6),
+				},
+			},
+			want: &RunnerSetResources{
+				Name:          "test-runner",
+				CPUMillis:     1000,
+				MemoryBytes:   2 * 1024 * 1024 * 1024,
+				CurrentMax:    6,
+				ConfiguredMax: 6,
+			},
+		},
+		{
+			name: "invalid max-runners annotation",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled:    "true",
+						config.AnnotationCPU:        "1000m",
+						config.AnnotationMemory:     "2Gi",
+						config.AnnotationMaxRunners: "many",
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "invalid max-runners annotation",
+		},
+		{
+			name: "negative max-runners annotation",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled:    "true",
+						config.AnnotationCPU:        "1000m",
+						config.AnnotationMemory:     "2Gi",
+						config.AnnotationMaxRunners: "-1",
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "max-runners must be non-negative",
+		},
+		{
+			name: "min-runners above configured max",
+			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-runner",
+					Annotations: map[string]string{
+						config.AnnotationEnabled:    "true",
+						config.AnnotationCPU:        "1000m",
+						config.AnnotationMemory:     "2Gi",
+						config.AnnotationMaxRunners: "3",
+						config.AnnotationMinRunners: "5",
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "min-runners 5 exceeds the configured max of 3 runners",
+		},
 		{
 			name: "nil maxRunners",
 			runnerSet: &actionsv1alpha1.AutoscalingRunnerSet{
@@ -314,6 +561,493 @@ func TestExtractRunnerSetResources(t *testing.T) {
 	}
 }
 
+func TestExtractRunnerSetResources_PodTemplateFootprint(t *testing.T) {
+	requests := func(cpu, memory string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+		}
+	}
+	// Pod template of the dind container mode, with the dind sidecar next to the runner
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "init-dind-externals", Resources: requests("100m", "64Mi")},
+			},
+			Containers: []corev1.Container{
+				{Name: "runner", Resources: requests("1000m", "2Gi")},
+				{Name: "dind", Resources: requests("1500m", "4Gi")},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		wantCPUMillis   int64
+		wantMemoryBytes int64
+		errContains     string
+	}{
+		{
+			name:            "all containers are counted",
+			wantCPUMillis:   2500,
+			wantMemoryBytes: 6 * 1024 * 1024 * 1024,
+		},
+		{
+			name:            "multiplier scales the footprint",
+			annotations:     map[string]string{config.AnnotationPodMultiplier: "1.5"},
+			wantCPUMillis:   3750,
+			wantMemoryBytes: 9 * 1024 * 1024 * 1024,
+		},
+		{
+			name: "multiplier does not scale annotations",
+			annotations: map[string]string{
+				config.AnnotationPodMultiplier: "2",
+				config.AnnotationCPU:           "4000m",
+			},
+			wantCPUMillis:   4000,
+			wantMemoryBytes: 12 * 1024 * 1024 * 1024,
+		},
+		{
+			name:        "invalid multiplier",
+			annotations: map[string]string{config.AnnotationPodMultiplier: "twice"},
+			errContains: "invalid pod-multiplier annotation",
+		},
+		{
+			name:        "multiplier below 1",
+			annotations: map[string]string{config.AnnotationPodMultiplier: "0.5"},
+			errContains: "pod-multiplier must be at least 1, got 0.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{config.AnnotationEnabled: "true"}
+			maps.Copy(annotations, tt.annotations)
+			runnerSet := &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-runner", Annotations: annotations},
+				Spec:       actionsv1alpha1.AutoscalingRunnerSetSpec{Template: template},
+			}
+
+			got, err := ExtractRunnerSetResources(runnerSet)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("ExtractRunnerSetResources() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractRunnerSetResources() unexpected error = %v", err)
+			}
+			if got.CPUMillis != tt.wantCPUMillis {
+				t.Errorf("CPUMillis = %v, want %v", got.CPUMillis, tt.wantCPUMillis)
+			}
+			if got.MemoryBytes != tt.wantMemoryBytes {
+				t.Errorf("MemoryBytes = %v, want %v", got.MemoryBytes, tt.wantMemoryBytes)
+			}
+		})
+	}
+}
+
+func TestExtractRunnerSetResources_ExtendedResources(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "runner",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("1000m"),
+						corev1.ResourceMemory: resource.MustParse("2Gi"),
+						"nvidia.com/gpu":      resource.MustParse("1"),
+						"hugepages-2Mi":       resource.MustParse("256Mi"),
+					},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		template    *corev1.PodTemplateSpec
+		want        ScalarResources
+		errContains string
+	}{
+		{
+			name: "from pod template",
+			want: ScalarResources{"nvidia.com/gpu": 1, "hugepages-2Mi": 256 * 1024 * 1024, corev1.ResourcePods: 1},
+		},
+		{
+			name:        "pod template scaled by multiplier",
+			annotations: map[string]string{config.AnnotationPodMultiplier: "2"},
+			want:        ScalarResources{"nvidia.com/gpu": 2, "hugepages-2Mi": 512 * 1024 * 1024, corev1.ResourcePods: 2},
+		},
+		{
+			name:        "annotation takes precedence",
+			annotations: map[string]string{config.AnnotationExtendedResources: "nvidia.com/gpu=2, example.com/fpga=1"},
+			want:        ScalarResources{"nvidia.com/gpu": 2, "example.com/fpga": 1, corev1.ResourcePods: 1},
+		},
+		{
+			name:        "empty annotation requests none",
+			annotations: map[string]string{config.AnnotationExtendedResources: ""},
+			want:        ScalarResources{corev1.ResourcePods: 1},
+		},
+		{
+			name:        "missing quantity",
+			annotations: map[string]string{config.AnnotationExtendedResources: "nvidia.com/gpu"},
+			errContains: `"nvidia.com/gpu" must be a name=quantity pair`,
+		},
+		{
+			name:        "native resource",
+			annotations: map[string]string{config.AnnotationExtendedResources: "cpu=2"},
+			errContains: `"cpu" is not an extended resource or hugepages`,
+		},
+		{
+			name:        "invalid quantity",
+			annotations: map[string]string{config.AnnotationExtendedResources: "nvidia.com/gpu=one"},
+			errContains: "invalid quantity for nvidia.com/gpu",
+		},
+		{
+			name:        "negative quantity",
+			annotations: map[string]string{config.AnnotationExtendedResources: "nvidia.com/gpu=-1"},
+			errContains: "nvidia.com/gpu must not be negative",
+		},
+		{
+			name:        "fractional quantity",
+			annotations: map[string]string{config.AnnotationExtendedResources: "nvidia.com/gpu=0.5"},
+			errContains: "nvidia.com/gpu must be a whole number, got 500m",
+		},
+		{
+			name:        "milli quantity",
+			annotations: map[string]string{config.AnnotationExtendedResources: "nvidia.com/gpu=100m"},
+			errContains: "nvidia.com/gpu must be a whole number, got 100m",
+		},
+		{
+			name:        "hugepages of any size",
+			annotations: map[string]string{config.AnnotationExtendedResources: "hugepages-2Mi=1.5Mi"},
+			want:        ScalarResources{"hugepages-2Mi": 1572864, corev1.ResourcePods: 1},
+		},
+		{
+			name: "fractional quantity in pod template",
+			template: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name: "runner",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("1000m"),
+								corev1.ResourceMemory: resource.MustParse("2Gi"),
+								"nvidia.com/gpu":      resource.MustParse("0.5"),
+							},
+						},
+					}},
+				},
+			},
+			errContains: "invalid pod template resources: nvidia.com/gpu must be a whole number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{config.AnnotationEnabled: "true"}
+			maps.Copy(annotations, tt.annotations)
+			runnerSet := &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-runner", Annotations: annotations},
+				Spec:       actionsv1alpha1.AutoscalingRunnerSetSpec{Template: template},
+			}
+			if tt.template != nil {
+				runnerSet.Spec.Template = *tt.template
+			}
+
+			got, err := ExtractRunnerSetResources(runnerSet)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("ExtractRunnerSetResources() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractRunnerSetResources() unexpected error = %v", err)
+			}
+			if !maps.Equal(got.ScalarResources, tt.want) {
+				t.Errorf("ScalarResources = %v, want %v", got.ScalarResources, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRunnerSetResources_PodsPerRunner(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        int64
+		errContains string
+	}{
+		{
+			name: "runner pod only",
+			want: 1,
+		},
+		{
+			name:        "pod multiplier rounded up",
+			annotations: map[string]string{config.AnnotationPodMultiplier: "1.5"},
+			want:        2,
+		},
+		{
+			name: "annotation takes precedence",
+			annotations: map[string]string{
+				config.AnnotationPodMultiplier: "2",
+				config.AnnotationPodsPerRunner: "3",
+			},
+			want: 3,
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{config.AnnotationPodsPerRunner: "two"},
+			errContains: "invalid pods-per-runner annotation",
+		},
+		{
+			name:        "less than one pod",
+			annotations: map[string]string{config.AnnotationPodsPerRunner: "0"},
+			errContains: "pods-per-runner must be at least 1, got 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "1000m",
+				config.AnnotationMemory:  "2Gi",
+			}
+			maps.Copy(annotations, tt.annotations)
+			runnerSet := &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-runner", Annotations: annotations},
+			}
+
+			got, err := ExtractRunnerSetResources(runnerSet)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("ExtractRunnerSetResources() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractRunnerSetResources() unexpected error = %v", err)
+			}
+			if got.ScalarResources[corev1.ResourcePods] != tt.want {
+				t.Errorf("pods = %d, want %d", got.ScalarResources[corev1.ResourcePods], tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRunnerSetResources_EphemeralStorage(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "runner",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:              resource.MustParse("1000m"),
+						corev1.ResourceMemory:           resource.MustParse("2Gi"),
+						corev1.ResourceEphemeralStorage: resource.MustParse("20Gi"),
+						"nvidia.com/gpu":                resource.MustParse("1"),
+					},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		template    *corev1.PodTemplateSpec
+		want        ScalarResources
+		errContains string
+	}{
+		{
+			name: "from pod template",
+			want: ScalarResources{corev1.ResourceEphemeralStorage: 20 * 1024 * 1024 * 1024, "nvidia.com/gpu": 1, corev1.ResourcePods: 1},
+		},
+		{
+			name:        "annotation takes precedence",
+			annotations: map[string]string{config.AnnotationEphemeralStorage: "50Gi"},
+			want:        ScalarResources{corev1.ResourceEphemeralStorage: 50 * 1024 * 1024 * 1024, "nvidia.com/gpu": 1, corev1.ResourcePods: 1},
+		},
+		{
+			name:        "raw bytes",
+			annotations: map[string]string{config.AnnotationEphemeralStorage: "1073741824"},
+			want:        ScalarResources{corev1.ResourceEphemeralStorage: 1024 * 1024 * 1024, "nvidia.com/gpu": 1, corev1.ResourcePods: 1},
+		},
+		{
+			name:        "extended resources annotation keeps storage from pod template",
+			annotations: map[string]string{config.AnnotationExtendedResources: "nvidia.com/gpu=2"},
+			want:        ScalarResources{corev1.ResourceEphemeralStorage: 20 * 1024 * 1024 * 1024, "nvidia.com/gpu": 2, corev1.ResourcePods: 1},
+		},
+		{
+			name: "not requested",
+			annotations: map[string]string{
+				config.AnnotationCPU:    "1000m",
+				config.AnnotationMemory: "2Gi",
+			},
+			template: &corev1.PodTemplateSpec{},
+			want:     ScalarResources{corev1.ResourcePods: 1},
+		},
+		{
+			name:        "ephemeral storage is not an extended resource",
+			annotations: map[string]string{config.AnnotationExtendedResources: "ephemeral-storage=20Gi"},
+			errContains: `"ephemeral-storage" is not an extended resource or hugepages`,
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{config.AnnotationEphemeralStorage: "lots"},
+			errContains: "invalid ephemeral-storage annotation",
+		},
+		{
+			name:        "zero annotation",
+			annotations: map[string]string{config.AnnotationEphemeralStorage: "0"},
+			errContains: "ephemeral-storage annotation must be positive, got 0",
+		},
+		{
+			name:        "negative annotation",
+			annotations: map[string]string{config.AnnotationEphemeralStorage: "-20Gi"},
+			errContains: "ephemeral-storage annotation must be positive, got -20Gi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{config.AnnotationEnabled: "true"}
+			maps.Copy(annotations, tt.annotations)
+			runnerSet := &actionsv1alpha1.AutoscalingRunnerSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-runner", Annotations: annotations},
+				Spec:       actionsv1alpha1.AutoscalingRunnerSetSpec{Template: template},
+			}
+			if tt.template != nil {
+				runnerSet.Spec.Template = *tt.template
+			}
+
+			got, err := ExtractRunnerSetResources(runnerSet)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("ExtractRunnerSetResources() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractRunnerSetResources() unexpected error = %v", err)
+			}
+			if !maps.Equal(got.ScalarResources, tt.want) {
+				t.Errorf("ScalarResources = %v, want %v", got.ScalarResources, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRunnerSetResources_SchedulingConstraints(t *testing.T) {
+	tolerations := []corev1.Toleration{
+		{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "xl", Effect: corev1.TaintEffectNoSchedule},
+	}
+	affinity := requiredNodeAffinity("pool", corev1.NodeSelectorOpIn, "xl")
+
+	runnerSet := &actionsv1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-runner",
+			Annotations: map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "4000m",
+				config.AnnotationMemory:  "8Gi",
+			},
+		},
+		Spec: actionsv1alpha1.AutoscalingRunnerSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					NodeSelector: map[string]string{"pool": "xl"},
+					Affinity:     affinity,
+					Tolerations:  tolerations,
+				},
+			},
+		},
+	}
+
+	got, err := ExtractRunnerSetResources(runnerSet)
+	if err != nil {
+		t.Fatalf("ExtractRunnerSetResources() unexpected error = %v", err)
+	}
+
+	if got.NodeSelector["pool"] != "xl" {
+		t.Errorf("NodeSelector = %v, want pool=xl", got.NodeSelector)
+	}
+	if got.Affinity != affinity {
+		t.Errorf("Affinity = %v, want %v", got.Affinity, affinity)
+	}
+	if len(got.Tolerations) != 1 || got.Tolerations[0] != tolerations[0] {
+		t.Errorf("Tolerations = %v, want %v", got.Tolerations, tolerations)
+	}
+}
+
+func TestExtractRunnerSetResources_DemandSignals(t *testing.T) {
+	runnerSet := &actionsv1alpha1.AutoscalingRunnerSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-runner",
+			Annotations: map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "2000m",
+				config.AnnotationMemory:  "4Gi",
+			},
+		},
+		Status: actionsv1alpha1.AutoscalingRunnerSetStatus{
+			CurrentRunners:          5,
+			PendingEphemeralRunners: 2,
+			RunningEphemeralRunners: 3,
+			FailedEphemeralRunners:  1,
+		},
+	}
+
+	got, err := ExtractRunnerSetResources(runnerSet)
+	if err != nil {
+		t.Fatalf("ExtractRunnerSetResources() unexpected error = %v", err)
+	}
+
+	if got.CurrentRunners != 5 {
+		t.Errorf("CurrentRunners = %v, want 5", got.CurrentRunners)
+	}
+	if got.PendingRunners != 2 {
+		t.Errorf("PendingRunners = %v, want 2", got.PendingRunners)
+	}
+	if got.RunningRunners != 3 {
+		t.Errorf("RunningRunners = %v, want 3", got.RunningRunners)
+	}
+	if got.DemandMax != nil {
+		t.Errorf("DemandMax = %v, want nil", *got.DemandMax)
+	}
+}
+
+func TestRunnerSetResources_maxRunnersCap(t *testing.T) {
+	tests := []struct {
+		name string
+		rs   *RunnerSetResources
+		want int
+	}{
+		{name: "no cap", rs: &RunnerSetResources{}, want: -1},
+		{name: "configured max", rs: &RunnerSetResources{ConfiguredMax: 10}, want: 10},
+		{name: "demand limit without configured max", rs: &RunnerSetResources{DemandMax: intPtr(4)}, want: 4},
+		{name: "demand limit below configured max", rs: &RunnerSetResources{ConfiguredMax: 10, DemandMax: intPtr(4)}, want: 4},
+		{name: "demand limit above configured max", rs: &RunnerSetResources{ConfiguredMax: 10, DemandMax: intPtr(20)}, want: 10},
+		{name: "demand limit of zero", rs: &RunnerSetResources{ConfiguredMax: 10, DemandMax: intPtr(0)}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rs.maxRunnersCap(); got != tt.want {
+				t.Errorf("maxRunnersCap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseResourceQuantityOrInt(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	"k8s.io/klog/v2"
+)
+
+// nodePool is a group of runner sets competing for capacity on the same nodes
+type nodePool struct {
+	runnerSets []*RunnerSetResources
+	nodes      []NodeCapacity
+}
+
+// canScheduleOn reports whether runner pods of a runner set can be scheduled onto a node.
+// It mirrors the scheduler's required checks: node selector, required node affinity,
+// NoSchedule/NoExecute taints not tolerated by the pod template, and extended resources
+// the node doesn't offer enough of.
+func canScheduleOn(rs *RunnerSetResources, node *NodeCapacity) bool {
+	if !fitsScalar(node.AllocatableScalar, rs.ScalarResources) {
+		return false
+	}
+
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeSelector: rs.NodeSelector,
+			Affinity:     rs.Affinity,
+		},
+	}
+	candidate := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   node.Name,
+			Labels: node.Labels,
+		},
+	}
+
+	// An invalid affinity can't be satisfied by any node
+	if matches, err := nodeaffinity.GetRequiredNodeAffinity(pod).Match(candidate); err != nil || !matches {
+		return false
+	}
+
+	_, untolerated := corev1helpers.FindMatchingUntoleratedTaint(klog.Background(), node.Taints, rs.Tolerations, func(taint *corev1.Taint) bool {
+		return taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute
+	}, false)
+	return !untolerated
+}
+
+// eligibleNodes returns the nodes a runner set can be scheduled onto
+func eligibleNodes(rs *RunnerSetResources, nodes []NodeCapacity) []NodeCapacity {
+	eligible := make([]NodeCapacity, 0, len(nodes))
+	for i := range nodes {
+		if canScheduleOn(rs, &nodes[i]) {
+			eligible = append(eligible, nodes[i])
+		}
+	}
+	return eligible
+}
+
+// partitionNodePools groups runner sets whose eligible nodes overlap into pools.
+// Runner sets in different pools are scheduled onto disjoint nodes, so they never compete
+// for the same capacity. Runner sets that can't be scheduled anywhere get a pool without nodes.
+func partitionNodePools(runnerSets []*RunnerSetResources, nodes []NodeCapacity) []nodePool {
+	// Union-find over runner sets, joining sets that share at least one eligible node
+	parent := make([]int, len(runnerSets))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// nodeOwner maps each node to the first runner set that can be scheduled onto it
+	nodeOwner := make([]int, len(nodes))
+	for n := range nodes {
+		nodeOwner[n] = -1
+		for i, rs := range runnerSets {
+			if !canScheduleOn(rs, &nodes[n]) {
+				continue
+			}
+			if nodeOwner[n] == -1 {
+				nodeOwner[n] = i
+				continue
+			}
+			parent[find(i)] = find(nodeOwner[n])
+		}
+	}
+
+	// Collect pools in the order of their first runner set for deterministic results
+	poolIndex := map[int]int{}
+	pools := []nodePool{}
+	for i, rs := range runnerSets {
+		root := find(i)
+		index, ok := poolIndex[root]
+		if !ok {
+			index = len(pools)
+			poolIndex[root] = index
+			pools = append(pools, nodePool{})
+		}
+		pools[index].runnerSets = append(pools[index].runnerSets, rs)
+	}
+	for n, owner := range nodeOwner {
+		// Nodes no runner set can use don't belong to any pool
+		if owner == -1 {
+			continue
+		}
+		index := poolIndex[find(owner)]
+		pools[index].nodes = append(pools[index].nodes, nodes[n])
+	}
+
+	return pools
+}
+
+// sumAvailable sums the available capacity of nodes
+func sumAvailable(nodes []NodeCapacity) (cpuMillis, memoryBytes int64, scalar ScalarResources) {
+	scalar = ScalarResources{}
+	for _, node := range nodes {
+		cpuMillis += node.AvailableCPUMillis
+		memoryBytes += node.AvailableMemoryBytes
+		scalar.add(node.AvailableScalar, 1)
+	}
+	return cpuMillis, memoryBytes, scalar
+}
@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCanScheduleOn(t *testing.T) {
+	xlTaint := corev1.Taint{Key: "pool", Value: "xl", Effect: corev1.TaintEffectNoSchedule}
+	xlToleration := corev1.Toleration{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "xl", Effect: corev1.TaintEffectNoSchedule}
+
+	tests := []struct {
+		name string
+		rs   *RunnerSetResources
+		node *NodeCapacity
+		want bool
+	}{
+		{
+			name: "no constraints on untainted node",
+			rs:   &RunnerSetResources{Name: "test"},
+			node: &NodeCapacity{Name: "node1"},
+			want: true,
+		},
+		{
+			name: "node selector matches",
+			rs:   &RunnerSetResources{Name: "test", NodeSelector: map[string]string{"pool": "xl"}},
+			node: &NodeCapacity{Name: "node1", Labels: map[string]string{"pool": "xl", "zone": "a"}},
+			want: true,
+		},
+		{
+			name: "node selector does not match",
+			rs:   &RunnerSetResources{Name: "test", NodeSelector: map[string]string{"pool": "xl"}},
+			node: &NodeCapacity{Name: "node1", Labels: map[string]string{"pool": "default"}},
+			want: false,
+		},
+		{
+			name: "required node affinity matches",
+			rs: &RunnerSetResources{
+				Name:     "test",
+				Affinity: requiredNodeAffinity("pool", corev1.NodeSelectorOpIn, "xl", "xxl"),
+			},
+			node: &NodeCapacity{Name: "node1", Labels: map[string]string{"pool": "xxl"}},
+			want: true,
+		},
+		{
+			name: "required node affinity does not match",
+			rs: &RunnerSetResources{
+				Name:     "test",
+				Affinity: requiredNodeAffinity("pool", corev1.NodeSelectorOpNotIn, "xl", "xxl"),
+			},
+			node: &NodeCapacity{Name: "node1", Labels: map[string]string{"pool": "xl"}},
+			want: false,
+		},
+		{
+			name: "untolerated NoSchedule taint",
+			rs:   &RunnerSetResources{Name: "test"},
+			node: &NodeCapacity{Name: "node1", Taints: []corev1.Taint{xlTaint}},
+			want: false,
+		},
+		{
+			name: "tolerated NoSchedule taint",
+			rs:   &RunnerSetResources{Name: "test", Tolerations: []corev1.Toleration{xlToleration}},
+			node: &NodeCapacity{Name: "node1", Taints: []corev1.Taint{xlTaint}},
+			want: true,
+		},
+		{
+			name: "untolerated NoExecute taint",
+			rs:   &RunnerSetResources{Name: "test"},
+			node: &NodeCapacity{Name: "node1", Taints: []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoExecute}}},
+			want: false,
+		},
+		{
+			name: "node offers requested extended resource",
+			rs:   &RunnerSetResources{Name: "test", ScalarResources: ScalarResources{"nvidia.com/gpu": 2}},
+			node: &NodeCapacity{Name: "node1", AllocatableScalar: ScalarResources{"nvidia.com/gpu": 4}},
+			want: true,
+		},
+		{
+			name: "node without requested extended resource",
+			rs:   &RunnerSetResources{Name: "test", ScalarResources: ScalarResources{"nvidia.com/gpu": 1}},
+			node: &NodeCapacity{Name: "node1"},
+			want: false,
+		},
+		{
+			name: "node offers too little of requested extended resource",
+			rs:   &RunnerSetResources{Name: "test", ScalarResources: ScalarResources{"nvidia.com/gpu": 2}},
+			node: &NodeCapacity{Name: "node1", AllocatableScalar: ScalarResources{"nvidia.com/gpu": 1}},
+			want: false,
+		},
+		{
+			name: "PreferNoSchedule taint does not prevent scheduling",
+			rs:   &RunnerSetResources{Name: "test"},
+			node: &NodeCapacity{Name: "node1", Taints: []corev1.Taint{{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule}}},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := canScheduleOn(tt.rs, tt.node)
+			if got != tt.want {
+				t.Errorf("canScheduleOn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartitionNodePools(t *testing.T) {
+	xlTaint := corev1.Taint{Key: "pool", Value: "xl", Effect: corev1.TaintEffectNoSchedule}
+	xlToleration := corev1.Toleration{Key: "pool", Operator: corev1.TolerationOpEqual, Value: "xl", Effect: corev1.TaintEffectNoSchedule}
+
+	nodes := []NodeCapacity{
+		{Name: "default-1", Labels: map[string]string{"pool": "default"}},
+		{Name: "default-2", Labels: map[string]string{"pool": "default"}},
+		{Name: "xl-1", Labels: map[string]string{"pool": "xl"}, Taints: []corev1.Taint{xlTaint}},
+	}
+
+	tests := []struct {
+		name       string
+		runnerSets []*RunnerSetResources
+		want       [][]string // runner set names and node names per pool, separated by "|"
+	}{
+		{
+			name: "unconstrained runner sets share a single pool",
+			runnerSets: []*RunnerSetResources{
+				{Name: "a"},
+				{Name: "b"},
+			},
+			want: [][]string{
+				{"a", "b", "|", "default-1", "default-2"},
+			},
+		},
+		{
+			name: "runner sets on disjoint node pools are separated",
+			runnerSets: []*RunnerSetResources{
+				{Name: "default"},
+				{Name: "xl", NodeSelector: map[string]string{"pool": "xl"}, Tolerations: []corev1.Toleration{xlToleration}},
+			},
+			want: [][]string{
+				{"default", "|", "default-1", "default-2"},
+				{"xl", "|", "xl-1"},
+			},
+		},
+		{
+			name: "overlapping node pools are joined",
+			runnerSets: []*RunnerSetResources{
+				{Name: "xl", NodeSelector: map[string]string{"pool": "xl"}, Tolerations: []corev1.Toleration{xlToleration}},
+				{Name: "default"},
+				{Name: "anywhere", Tolerations: []corev1.Toleration{xlToleration}},
+			},
+			want: [][]string{
+				{"xl", "default", "anywhere", "|", "default-1", "default-2", "xl-1"},
+			},
+		},
+		{
+			name: "unschedulable runner set gets a pool without nodes",
+			runnerSets: []*RunnerSetResources{
+				{Name: "default"},
+				{Name: "gpu", NodeSelector: map[string]string{"pool": "gpu"}},
+			},
+			want: [][]string{
+				{"default", "|", "default-1", "default-2"},
+				{"gpu", "|"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pools := partitionNodePools(tt.runnerSets, nodes)
+
+			got := make([][]string, 0, len(pools))
+			for _, pool := range pools {
+				names := []string{}
+				for _, rs := range pool.runnerSets {
+					names = append(names, rs.Name)
+				}
+				names = append(names, "|")
+				for _, node := range pool.nodes {
+					names = append(names, node.Name)
+				}
+				got = append(got, names)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("partitionNodePools() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if len(got[i]) != len(tt.want[i]) {
+					t.Errorf("pool %d = %v, want %v", i, got[i], tt.want[i])
+					continue
+				}
+				for j := range got[i] {
+					if got[i][j] != tt.want[i][j] {
+						t.Errorf("pool %d = %v, want %v", i, got[i], tt.want[i])
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+// Helper functions
+
+func requiredNodeAffinity(key string, operator corev1.NodeSelectorOperator, values ...string) *corev1.Affinity {
+	return &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: key, Operator: operator, Values: values},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+// RunnerSetStatus is the allocation status of a runner set, written as JSON to its status annotation
+type RunnerSetStatus struct {
+	// LastReconcileTime is when the reconciliation writing this status started. An otherwise unchanged
+	// status is only rewritten once per reconcile interval (see writeStatus).
+	LastReconcileTime metav1.Time `json:"lastReconcileTime"`
+
+	// ComputedMaxRunners is the maxRunners calculated by the allocation strategy
+	ComputedMaxRunners *int `json:"computedMaxRunners,omitempty"`
+
+	// AppliedMaxRunners is the maxRunners set on the runner set
+	AppliedMaxRunners *int `json:"appliedMaxRunners,omitempty"`
+
+	// LimitedBy is what limits maxRunners ("cpu", "memory", "pods", "ephemeral-storage", an extended resource name, "configured max", "demand" or "running jobs")
+	LimitedBy string `json:"limitedBy,omitempty"`
+
+	// FairShare is the share of its node pool's capacity (0-1) the runner set is entitled to by its weight
+	FairShare *float64 `json:"fairShare,omitempty"`
+
+	// SafetyCapped is set when maxRunners was raised to the current runners to protect running jobs
+	SafetyCapped bool `json:"safetyCapped"`
+
+	// Error is why the runner set's configuration couldn't be read; it is skipped until fixed
+	Error string `json:"error,omitempty"`
+}
+
+// setStatusAnnotation writes the status to the status annotation of a runner set
+func setStatusAnnotation(runnerSet *actionsv1alpha1.AutoscalingRunnerSet, status *RunnerSetStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+
+	if runnerSet.Annotations == nil {
+		runnerSet.Annotations = map[string]string{}
+	}
+	runnerSet.Annotations[config.AnnotationStatus] = string(data)
+	return nil
+}
+
+// statusCurrent reports whether the status annotation of a runner set already matches the status,
+// apart from a timestamp less than the refresh interval older
+func statusCurrent(runnerSet *actionsv1alpha1.AutoscalingRunnerSet, status *RunnerSetStatus, refreshInterval time.Duration) bool {
+	data, ok := runnerSet.Annotations[config.AnnotationStatus]
+	if !ok {
+		return false
+	}
+	current := RunnerSetStatus{}
+	if err := json.Unmarshal([]byte(data), &current); err != nil {
+		return false
+	}
+	if status.LastReconcileTime.Sub(current.LastReconcileTime.Time) >= refreshInterval {
+		return false
+	}
+
+	current.LastReconcileTime = status.LastReconcileTime
+	return reflect.DeepEqual(current, *status)
+}
+
+// writeStatus patches the status annotation of a runner set whose maxRunners doesn't change.
+// Failures are only logged, as the status is informational.
+//
+// To avoid patching every runner set on every reconciliation, a status that only differs in its timestamp
+// isn't written until the timestamp is a reconcile interval old.
+func (r *Reconciler) writeStatus(ctx context.Context, cfg *config.Config, runnerSet *actionsv1alpha1.AutoscalingRunnerSet, status *RunnerSetStatus) {
+	if cfg.DryRun || statusCurrent(runnerSet, status, cfg.ReconcileInterval) {
+		return
+	}
+
+	updated := runnerSet.DeepCopy()
+	err := setStatusAnnotation(updated, status)
+	if err == nil {
+		err = r.client.Patch(ctx, updated, client.MergeFrom(runnerSet))
+	}
+	if err != nil {
+		r.logger.Warn("failed to write runner set status",
+			"namespace", runnerSet.Namespace,
+			"name", runnerSet.Name,
+			"error", err)
+	}
+}
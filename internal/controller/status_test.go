@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestReconciler_ReconcileOnce_WritesStatus(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	busyPod := makePod("busy", "node1", "6000m", "4Gi", corev1.PodRunning)
+	annotations := func(priority string) map[string]string {
+		return map[string]string{
+			config.AnnotationEnabled:  "true",
+			config.AnnotationCPU:      "1000m",
+			config.AnnotationMemory:   "1Gi",
+			config.AnnotationPriority: priority,
+		}
+	}
+
+	// 3 runners fit (3.6 CPUs): 2 for the higher priority, 1 for the other, which still has 2 runners
+	high := makeRunnerSet("default", "high", 10, annotations("2"))
+	low := makeRunnerSet("default", "low", 10, annotations("1"))
+	low.Status.CurrentRunners = 2
+	invalid := makeRunnerSet("default", "invalid", 10, map[string]string{
+		config.AnnotationEnabled:  "true",
+		config.AnnotationCPU:      "1000m",
+		config.AnnotationMemory:   "1Gi",
+		config.AnnotationPriority: "high",
+	})
+
+	k8sClient := newFakeClient(t, &node, &busyPod, high, low, invalid)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+
+	highStatus := getRunnerSetStatus(t, k8sClient, "high")
+	if highStatus.LastReconcileTime.IsZero() {
+		t.Error("high lastReconcileTime is zero")
+	}
+	if *highStatus.ComputedMaxRunners != 2 || *highStatus.AppliedMaxRunners != 2 {
+		t.Errorf("high computed/applied maxRunners = %d/%d, want 2/2", *highStatus.ComputedMaxRunners, *highStatus.AppliedMaxRunners)
+	}
+	if highStatus.LimitedBy != limitingFactorCPU {
+		t.Errorf("high limitedBy = %q, want %q", highStatus.LimitedBy, limitingFactorCPU)
+	}
+	if *highStatus.FairShare < 0.66 || *highStatus.FairShare > 0.67 {
+		t.Errorf("high fairShare = %v, want 2/3", *highStatus.FairShare)
+	}
+	if highStatus.SafetyCapped {
+		t.Error("high safetyCapped = true, want false")
+	}
+
+	lowStatus := getRunnerSetStatus(t, k8sClient, "low")
+	if *lowStatus.ComputedMaxRunners != 1 || *lowStatus.AppliedMaxRunners != 2 {
+		t.Errorf("low computed/applied maxRunners = %d/%d, want 1/2", *lowStatus.ComputedMaxRunners, *lowStatus.AppliedMaxRunners)
+	}
+	if !lowStatus.SafetyCapped || lowStatus.LimitedBy != limitingFactorRunningJobs {
+		t.Errorf("low safetyCapped/limitedBy = %v/%q, want true/%q", lowStatus.SafetyCapped, lowStatus.LimitedBy, limitingFactorRunningJobs)
+	}
+
+	invalidStatus := getRunnerSetStatus(t, k8sClient, "invalid")
+	if !strings.Contains(invalidStatus.Error, "invalid priority annotation") {
+		t.Errorf("invalid error = %q, want invalid priority annotation", invalidStatus.Error)
+	}
+	if invalidStatus.ComputedMaxRunners != nil {
+		t.Errorf("invalid computedMaxRunners = %d, want none", *invalidStatus.ComputedMaxRunners)
+	}
+
+}
+
+func TestReconciler_ReconcileOnce_SkipsUnchangedStatus(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node, runnerSet)
+	cfg := config.DefaultConfig()
+	reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	written := getRunnerSet(t, k8sClient, "default", "ci")
+
+	// Only the timestamp would change, so the runner set isn't patched
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "ci"); got.ResourceVersion != written.ResourceVersion {
+		t.Errorf("resourceVersion = %s, want unchanged %s", got.ResourceVersion, written.ResourceVersion)
+	}
+
+	// Once the timestamp is a reconcile interval old, it is refreshed
+	refresh := *cfg
+	refresh.ReconcileInterval = time.Nanosecond
+	reconciler.UpdateConfig(&refresh)
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := getRunnerSet(t, k8sClient, "default", "ci"); got.ResourceVersion == written.ResourceVersion {
+		t.Error("resourceVersion unchanged, want status refreshed")
+	}
+}
+
+func TestReconciler_ReconcileOnce_DryRunWritesNoStatus(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node, runnerSet)
+	cfg := config.DefaultConfig()
+	cfg.DryRun = true
+	reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got, ok := getRunnerSet(t, k8sClient, "default", "ci").Annotations[config.AnnotationStatus]; ok {
+		t.Errorf("status annotation = %q in dry-run mode, want none", got)
+	}
+}
+
+func getRunnerSetStatus(t *testing.T, k8sClient client.Client, name string) *RunnerSetStatus {
+	t.Helper()
+
+	data, ok := getRunnerSet(t, k8sClient, "default", name).Annotations[config.AnnotationStatus]
+	if !ok {
+		t.Fatalf("runner set %s has no status annotation", name)
+	}
+	status := &RunnerSetStatus{}
+	if err := json.Unmarshal([]byte(data), status); err != nil {
+		t.Fatalf("failed to unmarshal status of runner set %s: %v", name, err)
+	}
+	return status
+}
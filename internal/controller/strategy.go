@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+// AllocationStrategy distributes available capacity among runner sets
+type AllocationStrategy interface {
+	// Name returns the name used to select the strategy
+	Name() string
+
+	// Allocate calculates maxRunners for the runner sets so they fit into the available capacity
+	Allocate(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error)
+}
+
+// NewAllocationStrategy returns the allocation strategy with the given name
+func NewAllocationStrategy(name string, allocator *Allocator) (AllocationStrategy, error) {
+	switch name {
+	case config.AllocationStrategyStrictPriority:
+		return &strictPriorityStrategy{allocator: allocator}, nil
+	case config.AllocationStrategyFairShare:
+		return &fairShareStrategy{allocator: allocator}, nil
+	case config.AllocationStrategyMaxMinFairness:
+		return &maxMinFairnessStrategy{allocator: allocator}, nil
+	case config.AllocationStrategyWeightedDRF:
+		return &weightedDRFStrategy{allocator: allocator}, nil
+	default:
+		return nil, fmt.Errorf("unknown allocation strategy %q", name)
+	}
+}
+
+// strictPriorityStrategy gives higher priority runner sets all the capacity they can use before lower priorities.
+// Lower priority runner sets can be starved completely.
+type strictPriorityStrategy struct {
+	allocator *Allocator
+}
+
+func (s *strictPriorityStrategy) Name() string {
+	return config.AllocationStrategyStrictPriority
+}
+
+func (s *strictPriorityStrategy) Allocate(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error) {
+	return s.allocator.Allocate(runnerSets, availableCPUMillis, availableMemoryBytes, availableScalar)
+}
+
+// fairShareStrategy splits capacity proportionally to priority weights and redistributes unused capacity by priority
+type fairShareStrategy struct {
+	allocator *Allocator
+}
+
+func (s *fairShareStrategy) Name() string {
+	return config.AllocationStrategyFairShare
+}
+
+func (s *fairShareStrategy) Allocate(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error) {
+	return s.allocator.AllocateFairShare(runnerSets, availableCPUMillis, availableMemoryBytes, availableScalar)
+}
+
+// maxMinFairnessStrategy equalizes the dominant resource share of all runner sets, ignoring priorities
+// except for breaking ties
+type maxMinFairnessStrategy struct {
+	allocator *Allocator
+}
+
+func (s *maxMinFairnessStrategy) Name() string {
+	return config.AllocationStrategyMaxMinFairness
+}
+
+func (s *maxMinFairnessStrategy) Allocate(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error) {
+	return s.allocator.AllocateMaxMinFairness(runnerSets, availableCPUMillis, availableMemoryBytes, availableScalar)
+}
+
+// weightedDRFStrategy equalizes the dominant resource share of all runner sets, weighted by priority
+type weightedDRFStrategy struct {
+	allocator *Allocator
+}
+
+func (s *weightedDRFStrategy) Name() string {
+	return config.AllocationStrategyWeightedDRF
+}
+
+func (s *weightedDRFStrategy) Allocate(runnerSets []*RunnerSetResources, availableCPUMillis, availableMemoryBytes int64, availableScalar ScalarResources) ([]RunnerSetAllocation, error) {
+	return s.allocator.AllocateWeightedDRF(runnerSets, availableCPUMillis, availableMemoryBytes, availableScalar)
+}
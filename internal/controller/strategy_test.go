@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestNewAllocationStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: config.AllocationStrategyStrictPriority},
+		{name: config.AllocationStrategyFairShare},
+		{name: config.AllocationStrategyMaxMinFairness},
+		{name: config.AllocationStrategyWeightedDRF},
+		{name: "round-robin", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := NewAllocationStrategy(tt.name, NewAllocator(testLogger()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAllocationStrategy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if strategy.Name() != tt.name {
+				t.Errorf("Name() = %q, want %q", strategy.Name(), tt.name)
+			}
+		})
+	}
+}
+
+func TestAllocationStrategies_StarvationTradeOff(t *testing.T) {
+	// Two identical runner sets with very different priorities competing for 10 CPUs
+	runnerSets := []*RunnerSetResources{
+		{Name: "high-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 9, ConfiguredMax: 20},
+		{Name: "low-priority", CPUMillis: 1000, MemoryBytes: 1 * 1024 * 1024 * 1024, Priority: 1, ConfiguredMax: 20},
+	}
+
+	tests := []struct {
+		strategy string
+		want     map[string]int // name -> maxRunners
+	}{
+		{
+			strategy: config.AllocationStrategyStrictPriority,
+			want:     map[string]int{"high-priority": 10, "low-priority": 0}, // Low priority is starved
+		},
+		{
+			strategy: config.AllocationStrategyFairShare,
+			want:     map[string]int{"high-priority": 9, "low-priority": 1}, // Proportional to priority weights
+		},
+		{
+			strategy: config.AllocationStrategyMaxMinFairness,
+			want:     map[string]int{"high-priority": 5, "low-priority": 5}, // Equal dominant shares
+		},
+		{
+			strategy: config.AllocationStrategyWeightedDRF,
+			want:     map[string]int{"high-priority": 9, "low-priority": 1}, // Dominant shares proportional to priority weights
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			strategy, err := NewAllocationStrategy(tt.strategy, NewAllocator(testLogger()))
+			if err != nil {
+				t.Fatalf("NewAllocationStrategy() error = %v", err)
+			}
+
+			allocations, err := strategy.Allocate(runnerSets, 10000, 40*1024*1024*1024, nil)
+			if err != nil {
+				t.Fatalf("Allocate() error = %v", err)
+			}
+
+			for _, alloc := range allocations {
+				if alloc.MaxRunners != tt.want[alloc.Name] {
+					t.Errorf("allocation for %s = %v, want %v", alloc.Name, alloc.MaxRunners, tt.want[alloc.Name])
+				}
+			}
+		})
+	}
+}
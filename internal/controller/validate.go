@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+)
+
+// InvalidRunnerSet is a runner set that opted in to autoscaling, but whose annotations are invalid
+type InvalidRunnerSet struct {
+	Namespace string
+	Name      string
+	Err       error
+}
+
+// ValidateRunnerSet checks the autoscaling annotations of a runner set by the same rules the reconciler
+// applies, so a runner set passing validation is never skipped as misconfigured.
+// Runner sets not opted in to autoscaling return ErrAutoscalingNotEnabled.
+func ValidateRunnerSet(rs *actionsv1alpha1.AutoscalingRunnerSet) error {
+	_, err := ExtractRunnerSetResources(rs)
+	return err
+}
+
+// Validate checks the configuration of all runner sets in the configured namespaces without changing anything.
+// It returns the number of valid runner sets opted in to autoscaling and the misconfigured ones.
+func (r *Reconciler) Validate(ctx context.Context) (int, []InvalidRunnerSet, error) {
+	runnerSets, err := r.listRunnerSets(ctx, r.config.Load())
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list runner sets: %w", err)
+	}
+
+	valid := 0
+	invalid := []InvalidRunnerSet{}
+	for i := range runnerSets {
+		err := ValidateRunnerSet(&runnerSets[i])
+		switch {
+		case errors.Is(err, ErrAutoscalingNotEnabled):
+			continue
+		case err != nil:
+			invalid = append(invalid, InvalidRunnerSet{
+				Namespace: runnerSets[i].Namespace,
+				Name:      runnerSets[i].Name,
+				Err:       err,
+			})
+		default:
+			valid++
+		}
+	}
+
+	return valid, invalid, nil
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestReconciler_Validate(t *testing.T) {
+	valid := makeRunnerSet("default", "valid", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+	typo := makeRunnerSet("default", "typo", 10, map[string]string{
+		config.AnnotationEnabled:  "true",
+		config.AnnotationCPU:      "2000m",
+		config.AnnotationMemory:   "4Gi",
+		config.AnnotationPriority: "high",
+	})
+	missingResources := makeRunnerSet("team", "missing-resources", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+	})
+	overCap := makeRunnerSet("default", "over-cap", 3, map[string]string{
+		config.AnnotationEnabled:    "true",
+		config.AnnotationCPU:        "2000m",
+		config.AnnotationMemory:     "4Gi",
+		config.AnnotationMinRunners: "5",
+	})
+	zeroCPU := makeRunnerSet("default", "zero-cpu", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "0",
+		config.AnnotationMemory:  "4Gi",
+	})
+	notOptedIn := makeRunnerSet("default", "not-opted-in", 10, map[string]string{
+		config.AnnotationPriority: "high",
+	})
+
+	reconciler := NewReconciler(newFakeClient(t, valid, typo, missingResources, overCap, zeroCPU, notOptedIn), testLogger(), config.DefaultConfig())
+
+	validCount, invalid, err := reconciler.Validate(context.Background())
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if validCount != 1 {
+		t.Errorf("Validate() valid = %d, want 1", validCount)
+	}
+
+	wantErrors := map[string]string{
+		"default/typo":           "invalid priority annotation",
+		"team/missing-resources": "CPU not specified",
+		"default/over-cap":       "min-runners 5 exceeds the configured max of 3 runners",
+		"default/zero-cpu":       "CPU annotation must be positive",
+	}
+	if len(invalid) != len(wantErrors) {
+		t.Fatalf("Validate() invalid = %v, want %d runner sets", invalid, len(wantErrors))
+	}
+	for _, runnerSet := range invalid {
+		key := runnerSet.Namespace + "/" + runnerSet.Name
+		want, ok := wantErrors[key]
+		if !ok {
+			t.Errorf("Validate() reported unexpected runner set %s", key)
+			continue
+		}
+		if !strings.Contains(runnerSet.Err.Error(), want) {
+			t.Errorf("Validate() error for %s = %v, want containing %q", key, runnerSet.Err, want)
+		}
+	}
+}
+
+func TestReconciler_ReconcileOnce_CountsInvalidRunnerSets(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	typo := makeRunnerSet("default", "typo", 10, map[string]string{
+		config.AnnotationEnabled:  "true",
+		config.AnnotationCPU:      "2000m",
+		config.AnnotationMemory:   "4Gi",
+		config.AnnotationPriority: "high",
+	})
+	// Rejected by validation as well, so the reconciler must not manage it either
+	overCap := makeRunnerSet("default", "over-cap", 3, map[string]string{
+		config.AnnotationEnabled:    "true",
+		config.AnnotationCPU:        "2000m",
+		config.AnnotationMemory:     "4Gi",
+		config.AnnotationMinRunners: "5",
+	})
+	notOptedIn := makeRunnerSet("default", "not-opted-in", 10, nil)
+
+	k8sClient := newFakeClient(t, &node, typo, overCap, notOptedIn)
+	reconciler := NewReconciler(k8sClient, testLogger(), config.DefaultConfig())
+
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := testutil.ToFloat64(reconciler.metrics.invalidRunnerSets); got != 2 {
+		t.Errorf("invalid runner sets = %v, want 2", got)
+	}
+
+	// Fixing the annotations clears the count
+	fixed := getRunnerSet(t, k8sClient, "default", "typo")
+	fixed.Annotations[config.AnnotationPriority] = "100"
+	if err := k8sClient.Update(context.Background(), fixed); err != nil {
+		t.Fatalf("failed to update runner set: %v", err)
+	}
+	fixed = getRunnerSet(t, k8sClient, "default", "over-cap")
+	fixed.Annotations[config.AnnotationMinRunners] = "3"
+	if err := k8sClient.Update(context.Background(), fixed); err != nil {
+		t.Fatalf("failed to update runner set: %v", err)
+	}
+	if err := reconciler.ReconcileOnce(context.Background()); err != nil {
+		t.Fatalf("ReconcileOnce() error = %v", err)
+	}
+	if got := testutil.ToFloat64(reconciler.metrics.invalidRunnerSets); got != 0 {
+		t.Errorf("invalid runner sets after fix = %v, want 0", got)
+	}
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+// watch describes a resource whose changes trigger a reconciliation
+type watch struct {
+	object client.Object
+
+	// changed reports whether an update is relevant for capacity allocation
+	changed func(oldObj, newObj client.Object) bool
+}
+
+// SetupWithManager registers watches on the resources affecting capacity allocation, registers the
+// metrics, event recorder and health checks and adds the reconciliation loop to the manager. Reads of the reconciler's client are served from the
+// manager's informer caches instead of the API server.
+func (r *Reconciler) SetupWithManager(ctx context.Context, mgr manager.Manager) error {
+	watches := []watch{
+		{object: &corev1.Node{}, changed: nodeChanged},
+		{object: &corev1.Pod{}, changed: podChanged},
+		{object: &actionsv1alpha1.AutoscalingRunnerSet{}, changed: runnerSetChanged},
+	}
+	if r.config.Load().DemandAware {
+		watches = append(watches, watch{object: &actionsv1alpha1.EphemeralRunnerSet{}, changed: ephemeralRunnerSetChanged})
+	}
+
+	for _, w := range watches {
+		informer, err := mgr.GetCache().GetInformer(ctx, w.object)
+		if err != nil {
+			return fmt.Errorf("failed to get informer for %T: %w", w.object, err)
+		}
+		if _, err := informer.AddEventHandler(r.eventHandler(w)); err != nil {
+			return fmt.Errorf("failed to add event handler for %T: %w", w.object, err)
+		}
+	}
+
+	// Metrics are served by the manager's metrics server
+	if err := r.metrics.Register(ctrlmetrics.Registry); err != nil {
+		return err
+	}
+
+	// Events are recorded on runner sets to explain scaling decisions
+	r.recorder = mgr.GetEventRecorderFor("gha-runner-autoscaler-controller")
+
+	// Health probes are served by the manager's health probe server
+	r.elected = mgr.Elected()
+	if err := mgr.AddHealthzCheck("reconcile", r.Healthz); err != nil {
+		return fmt.Errorf("failed to add liveness check: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("reconcile", r.Readyz); err != nil {
+		return fmt.Errorf("failed to add readiness check: %w", err)
+	}
+
+	if err := mgr.Add(r); err != nil {
+		return fmt.Errorf("failed to add reconciler to manager: %w", err)
+	}
+	return nil
+}
+
+// eventHandler returns an informer event handler triggering a reconciliation for relevant changes
+func (r *Reconciler) eventHandler(w watch) toolscache.ResourceEventHandler {
+	kind := reflect.TypeOf(w.object).Elem().Name()
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			r.Trigger(kind + " added")
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldClientObj, oldOk := oldObj.(client.Object)
+			newClientObj, newOk := newObj.(client.Object)
+			if oldOk && newOk && !w.changed(oldClientObj, newClientObj) {
+				return
+			}
+			r.Trigger(kind + " updated")
+		},
+		DeleteFunc: func(obj any) {
+			r.Trigger(kind + " deleted")
+		},
+	}
+}
+
+// nodeChanged reports whether a node update affects its capacity.
+// Nodes update their status every few seconds for heartbeats, which must not trigger reconciliations.
+func nodeChanged(oldObj, newObj client.Object) bool {
+	oldNode, oldOk := oldObj.(*corev1.Node)
+	newNode, newOk := newObj.(*corev1.Node)
+	if !oldOk || !newOk {
+		return true
+	}
+
+	return isNodeReady(*oldNode) != isNodeReady(*newNode) ||
+		!reflect.DeepEqual(oldNode.Status.Allocatable, newNode.Status.Allocatable) ||
+		!reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+		!reflect.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints) ||
+		oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable
+}
+
+// podChanged reports whether a pod update affects the capacity it uses.
+// Requests only count once a pod is bound to a node and stop counting when it terminates.
+func podChanged(oldObj, newObj client.Object) bool {
+	oldPod, oldOk := oldObj.(*corev1.Pod)
+	newPod, newOk := newObj.(*corev1.Pod)
+	if !oldOk || !newOk {
+		return true
+	}
+
+	return oldPod.Spec.NodeName != newPod.Spec.NodeName ||
+		oldPod.Status.Phase != newPod.Status.Phase ||
+		!equality.Semantic.DeepEqual(podRequests(oldPod), podRequests(newPod))
+}
+
+// runnerSetChanged reports whether a runner set update affects its allocation.
+// Annotations and spec changes change its configuration, status changes its demand.
+// Patches of maxRunners by the controller itself trigger one more reconciliation, which finds nothing to change.
+// The status annotation is written on every reconciliation and ignored, so it doesn't trigger the next one.
+func runnerSetChanged(oldObj, newObj client.Object) bool {
+	oldRunnerSet, oldOk := oldObj.(*actionsv1alpha1.AutoscalingRunnerSet)
+	newRunnerSet, newOk := newObj.(*actionsv1alpha1.AutoscalingRunnerSet)
+	if !oldOk || !newOk {
+		return true
+	}
+
+	return !reflect.DeepEqual(withoutStatus(oldRunnerSet.Annotations), withoutStatus(newRunnerSet.Annotations)) ||
+		oldRunnerSet.Generation != newRunnerSet.Generation ||
+		oldRunnerSet.Status != newRunnerSet.Status
+}
+
+// withoutStatus returns the annotations without the status annotation written by the controller
+func withoutStatus(annotations map[string]string) map[string]string {
+	if _, ok := annotations[config.AnnotationStatus]; !ok {
+		return annotations
+	}
+
+	filtered := make(map[string]string, len(annotations)-1)
+	for key, value := range annotations {
+		if key != config.AnnotationStatus {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
+
+// ephemeralRunnerSetChanged reports whether the listener requested a different number of runners
+func ephemeralRunnerSetChanged(oldObj, newObj client.Object) bool {
+	oldEphemeralRunnerSet, oldOk := oldObj.(*actionsv1alpha1.EphemeralRunnerSet)
+	newEphemeralRunnerSet, newOk := newObj.(*actionsv1alpha1.EphemeralRunnerSet)
+	if !oldOk || !newOk {
+		return true
+	}
+
+	return oldEphemeralRunnerSet.Spec.Replicas != newEphemeralRunnerSet.Spec.Replicas
+}
@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestNodeChanged(t *testing.T) {
+	base := makeNode("node1", "8000m", "16Gi", corev1.ConditionTrue)
+
+	tests := []struct {
+		name   string
+		modify func(node *corev1.Node)
+		want   bool
+	}{
+		{
+			name: "heartbeat only",
+			modify: func(node *corev1.Node) {
+				node.Status.Conditions[0].LastHeartbeatTime.Time = time.Now()
+				node.ResourceVersion = "2"
+			},
+			want: false,
+		},
+		{
+			name: "becomes not ready",
+			modify: func(node *corev1.Node) {
+				node.Status.Conditions[0].Status = corev1.ConditionFalse
+			},
+			want: true,
+		},
+		{
+			name: "allocatable changes",
+			modify: func(node *corev1.Node) {
+				node.Status.Allocatable[corev1.ResourceCPU] = resource.MustParse("16000m")
+			},
+			want: true,
+		},
+		{
+			name: "labels change",
+			modify: func(node *corev1.Node) {
+				node.Labels = map[string]string{"pool": "xl"}
+			},
+			want: true,
+		},
+		{
+			name: "taints change",
+			modify: func(node *corev1.Node) {
+				node.Spec.Taints = []corev1.Taint{{Key: "pool", Effect: corev1.TaintEffectNoSchedule}}
+			},
+			want: true,
+		},
+		{
+			name: "cordoned",
+			modify: func(node *corev1.Node) {
+				node.Spec.Unschedulable = true
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base.DeepCopy()
+			tt.modify(updated)
+			if got := nodeChanged(&base, updated); got != tt.want {
+				t.Errorf("nodeChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodChanged(t *testing.T) {
+	base := makePod("pod1", "", "1000m", "1Gi", corev1.PodPending)
+
+	tests := []struct {
+		name   string
+		modify func(pod *corev1.Pod)
+		want   bool
+	}{
+		{
+			name: "condition only",
+			modify: func(pod *corev1.Pod) {
+				pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse}}
+			},
+			want: false,
+		},
+		{
+			name: "bound to a node",
+			modify: func(pod *corev1.Pod) {
+				pod.Spec.NodeName = "node1"
+			},
+			want: true,
+		},
+		{
+			name: "phase changes",
+			modify: func(pod *corev1.Pod) {
+				pod.Status.Phase = corev1.PodSucceeded
+			},
+			want: true,
+		},
+		{
+			name: "requests are resized",
+			modify: func(pod *corev1.Pod) {
+				pod.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("2000m")
+			},
+			want: true,
+		},
+		{
+			name: "init container requests more",
+			modify: func(pod *corev1.Pod) {
+				pod.Spec.InitContainers = []corev1.Container{{
+					Name: "init",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4000m")},
+					},
+				}}
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base.DeepCopy()
+			tt.modify(updated)
+			if got := podChanged(&base, updated); got != tt.want {
+				t.Errorf("podChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunnerSetChanged(t *testing.T) {
+	base := makeRunnerSet("default", "ci", 10, map[string]string{config.AnnotationEnabled: "true"})
+
+	tests := []struct {
+		name   string
+		modify func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet)
+		want   bool
+	}{
+		{
+			name: "resource version only",
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.ResourceVersion = "2"
+			},
+			want: false,
+		},
+		{
+			name: "annotations change",
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Annotations[config.AnnotationPriority] = "100"
+			},
+			want: true,
+		},
+		{
+			name: "status annotation written by the controller",
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Annotations[config.AnnotationStatus] = `{"safetyCapped":false}`
+			},
+			want: false,
+		},
+		{
+			name: "spec changes",
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Generation++
+			},
+			want: true,
+		},
+		{
+			name: "status changes",
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Status.CurrentRunners = 3
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base.DeepCopy()
+			tt.modify(updated)
+			if got := runnerSetChanged(base, updated); got != tt.want {
+				t.Errorf("runnerSetChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconciler_TriggerDoesNotBlock(t *testing.T) {
+	reconciler := NewReconciler(newFakeClient(t), testLogger(), config.DefaultConfig())
+
+	done := make(chan struct{})
+	go func() {
+		for range 100 {
+			reconciler.Trigger("test")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Trigger() blocked without a running reconciliation loop")
+	}
+	if len(reconciler.triggers) != 1 {
+		t.Errorf("pending triggers = %d, want 1", len(reconciler.triggers))
+	}
+}
+
+func TestReconciler_Run_ReconcilesOnTrigger(t *testing.T) {
+	node := makeNode("node1", "10000m", "20Gi", corev1.ConditionTrue)
+	runnerSet := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2000m",
+		config.AnnotationMemory:  "4Gi",
+	})
+
+	k8sClient := newFakeClient(t, &node, runnerSet)
+	cfg := config.DefaultConfig()
+	cfg.ReconcileInterval = time.Hour // Only the initial reconciliation and triggers
+	cfg.ReconcileDebounce = 10 * time.Millisecond
+	reconciler := NewReconciler(k8sClient, testLogger(), cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- reconciler.Run(ctx)
+	}()
+
+	// The initial reconciliation runs immediately: 10 * 0.9 = 9 CPUs
+	waitForMaxRunners(t, k8sClient, "default", "ci", 4)
+
+	// A busy pod is scheduled, which is reported by the pod watch
+	busyPod := makePod("busy", "node1", "6000m", "4Gi", corev1.PodRunning)
+	if err := k8sClient.Create(ctx, &busyPod); err != nil {
+		t.Fatalf("failed to create pod: %v", err)
+	}
+	reconciler.Trigger("Pod added")
+
+	// (10 - 6) * 0.9 = 3.6 CPUs
+	waitForMaxRunners(t, k8sClient, "default", "ci", 1)
+
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != context.Canceled {
+			t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not stop after the context was canceled")
+	}
+}
+
+// Helper functions
+
+func waitForMaxRunners(t *testing.T, k8sClient client.Client, namespace, name string, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		runnerSet := getRunnerSet(t, k8sClient, namespace, name)
+		if runnerSet.Spec.MaxRunners != nil && *runnerSet.Spec.MaxRunners == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("maxRunners of %s/%s = %v, want %d", namespace, name, *runnerSet.Spec.MaxRunners, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
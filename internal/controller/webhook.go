@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+// RunnerSetWebhookPath is the path the validating webhook for AutoscalingRunnerSets is served on
+const RunnerSetWebhookPath = "/validate-actions-github-com-v1alpha1-autoscalingrunnerset"
+
+// RunnerSetValidator is a validating admission webhook rejecting AutoscalingRunnerSets with invalid
+// autoscaling annotations, so misconfigurations are caught when applied instead of at reconcile time
+type RunnerSetValidator struct{}
+
+var _ admission.CustomValidator = &RunnerSetValidator{}
+
+// SetupWebhookWithManager registers the validating webhook with the manager's webhook server
+func SetupWebhookWithManager(mgr manager.Manager) error {
+	err := builder.WebhookManagedBy(mgr).
+		For(&actionsv1alpha1.AutoscalingRunnerSet{}).
+		WithValidator(&RunnerSetValidator{}).
+		WithValidatorCustomPath(RunnerSetWebhookPath).
+		Complete()
+	if err != nil {
+		return fmt.Errorf("failed to register runner set webhook: %w", err)
+	}
+	return nil
+}
+
+// ValidateCreate rejects new runner sets opted in to autoscaling with invalid annotations
+func (v *RunnerSetValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	runnerSet, ok := obj.(*actionsv1alpha1.AutoscalingRunnerSet)
+	if !ok {
+		return nil, fmt.Errorf("expected an AutoscalingRunnerSet, got %T", obj)
+	}
+	return nil, validateAdmission(runnerSet)
+}
+
+// ValidateUpdate rejects updates leaving a runner set opted in to autoscaling with invalid annotations.
+//
+// Runner sets that were already invalid, e.g. because they were created before the webhook was enabled,
+// can still be updated as long as the autoscaler annotations don't change. Otherwise ARC and the controller
+// couldn't update them anymore, e.g. to remove finalizers or to write the status annotation.
+func (v *RunnerSetValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldRunnerSet, ok := oldObj.(*actionsv1alpha1.AutoscalingRunnerSet)
+	if !ok {
+		return nil, fmt.Errorf("expected an AutoscalingRunnerSet, got %T", oldObj)
+	}
+	newRunnerSet, ok := newObj.(*actionsv1alpha1.AutoscalingRunnerSet)
+	if !ok {
+		return nil, fmt.Errorf("expected an AutoscalingRunnerSet, got %T", newObj)
+	}
+
+	err := validateAdmission(newRunnerSet)
+	if err == nil {
+		return nil, nil
+	}
+
+	if validateAdmission(oldRunnerSet) != nil && reflect.DeepEqual(autoscalerAnnotations(oldRunnerSet), autoscalerAnnotations(newRunnerSet)) {
+		return admission.Warnings{err.Error()}, nil
+	}
+	return nil, err
+}
+
+// ValidateDelete allows all deletions
+func (v *RunnerSetValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateAdmission validates a runner set, accepting runner sets not opted in to autoscaling
+func validateAdmission(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) error {
+	err := ValidateRunnerSet(runnerSet)
+	if err == nil || errors.Is(err, ErrAutoscalingNotEnabled) {
+		return nil
+	}
+	return fmt.Errorf("invalid autoscaling configuration: %w", err)
+}
+
+// autoscalerAnnotations returns the autoscaler annotations set by operators, without the status written by the controller
+func autoscalerAnnotations(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) map[string]string {
+	annotations := map[string]string{}
+	for key, value := range runnerSet.Annotations {
+		if strings.HasPrefix(key, config.AnnotationPrefix) && key != config.AnnotationStatus {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	actionsv1alpha1 "github.com/actions/actions-runner-controller/apis/actions.github.com/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/kula-app/gha-runner-autoscaler-controller/internal/config"
+)
+
+func TestRunnerSetValidator_Create(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		maxRunners  int
+		wantAllowed bool
+		wantMessage string
+	}{
+		{
+			name: "valid annotations",
+			annotations: map[string]string{
+				config.AnnotationEnabled:    "true",
+				config.AnnotationCPU:        "2",
+				config.AnnotationMemory:     "4Gi",
+				config.AnnotationPriority:   "100",
+				config.AnnotationMinRunners: "2",
+			},
+			maxRunners:  10,
+			wantAllowed: true,
+		},
+		{
+			name: "not opted in",
+			annotations: map[string]string{
+				config.AnnotationCPU: "lots",
+			},
+			maxRunners:  10,
+			wantAllowed: true,
+		},
+		{
+			name: "unparseable cpu",
+			annotations: map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "lots",
+				config.AnnotationMemory:  "4Gi",
+			},
+			maxRunners:  10,
+			wantMessage: "invalid CPU annotation",
+		},
+		{
+			name: "unparseable memory",
+			annotations: map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "2",
+				config.AnnotationMemory:  "4 GB",
+			},
+			maxRunners:  10,
+			wantMessage: "invalid memory annotation",
+		},
+		{
+			name: "zero cpu",
+			annotations: map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "0",
+				config.AnnotationMemory:  "4Gi",
+			},
+			maxRunners:  10,
+			wantMessage: "CPU annotation must be positive, got 0",
+		},
+		{
+			name: "negative cpu",
+			annotations: map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "-2000m",
+				config.AnnotationMemory:  "4Gi",
+			},
+			maxRunners:  10,
+			wantMessage: "CPU annotation must be positive, got -2000m",
+		},
+		{
+			name: "zero memory",
+			annotations: map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "2",
+				config.AnnotationMemory:  "0",
+			},
+			maxRunners:  10,
+			wantMessage: "memory annotation must be positive, got 0",
+		},
+		{
+			name: "negative memory",
+			annotations: map[string]string{
+				config.AnnotationEnabled: "true",
+				config.AnnotationCPU:     "2",
+				config.AnnotationMemory:  "-4Gi",
+			},
+			maxRunners:  10,
+			wantMessage: "memory annotation must be positive, got -4Gi",
+		},
+		{
+			name: "zero ephemeral storage",
+			annotations: map[string]string{
+				config.AnnotationEnabled:          "true",
+				config.AnnotationCPU:              "2",
+				config.AnnotationMemory:           "4Gi",
+				config.AnnotationEphemeralStorage: "0",
+			},
+			maxRunners:  10,
+			wantMessage: "ephemeral-storage annotation must be positive, got 0",
+		},
+		{
+			name: "negative ephemeral storage",
+			annotations: map[string]string{
+				config.AnnotationEnabled:          "true",
+				config.AnnotationCPU:              "2",
+				config.AnnotationMemory:           "4Gi",
+				config.AnnotationEphemeralStorage: "-20Gi",
+			},
+			maxRunners:  10,
+			wantMessage: "ephemeral-storage annotation must be positive, got -20Gi",
+		},
+		{
+			name: "negative min runners",
+			annotations: map[string]string{
+				config.AnnotationEnabled:    "true",
+				config.AnnotationCPU:        "2",
+				config.AnnotationMemory:     "4Gi",
+				config.AnnotationMinRunners: "-1",
+			},
+			maxRunners:  10,
+			wantMessage: "min-runners must be non-negative",
+		},
+		{
+			name: "min runners above spec maxRunners",
+			annotations: map[string]string{
+				config.AnnotationEnabled:    "true",
+				config.AnnotationCPU:        "2",
+				config.AnnotationMemory:     "4Gi",
+				config.AnnotationMinRunners: "5",
+			},
+			maxRunners:  3,
+			wantMessage: "min-runners 5 exceeds the configured max of 3 runners",
+		},
+		{
+			name: "min runners above max-runners annotation",
+			annotations: map[string]string{
+				config.AnnotationEnabled:    "true",
+				config.AnnotationCPU:        "2",
+				config.AnnotationMemory:     "4Gi",
+				config.AnnotationMinRunners: "5",
+				config.AnnotationMaxRunners: "4",
+			},
+			maxRunners:  10,
+			wantMessage: "min-runners 5 exceeds the configured max of 4 runners",
+		},
+		{
+			name: "min runners without cap",
+			annotations: map[string]string{
+				config.AnnotationEnabled:    "true",
+				config.AnnotationCPU:        "2",
+				config.AnnotationMemory:     "4Gi",
+				config.AnnotationMinRunners: "5",
+				config.AnnotationMaxRunners: "0",
+			},
+			maxRunners:  3,
+			wantAllowed: true,
+		},
+	}
+
+	handler := newRunnerSetWebhook(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runnerSet := makeRunnerSet("default", "ci", tt.maxRunners, tt.annotations)
+			response := handler.Handle(context.Background(), admissionRequest(t, admissionv1.Create, runnerSet, nil))
+			assertAdmission(t, response, tt.wantAllowed, tt.wantMessage)
+		})
+	}
+}
+
+func TestRunnerSetValidator_Update(t *testing.T) {
+	valid := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled: "true",
+		config.AnnotationCPU:     "2",
+		config.AnnotationMemory:  "4Gi",
+	})
+	invalid := makeRunnerSet("default", "ci", 10, map[string]string{
+		config.AnnotationEnabled:  "true",
+		config.AnnotationCPU:      "2",
+		config.AnnotationMemory:   "4Gi",
+		config.AnnotationPriority: "high",
+	})
+
+	tests := []struct {
+		name        string
+		oldObj      *actionsv1alpha1.AutoscalingRunnerSet
+		modify      func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet)
+		wantAllowed bool
+		wantMessage string
+	}{
+		{
+			name:   "breaking a valid runner set",
+			oldObj: valid,
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Annotations[config.AnnotationCPU] = "2 cores"
+			},
+			wantMessage: "invalid CPU annotation",
+		},
+		{
+			name:   "fixing an invalid runner set",
+			oldObj: invalid,
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Annotations[config.AnnotationPriority] = "100"
+			},
+			wantAllowed: true,
+		},
+		{
+			name:   "changing an invalid runner set's annotations",
+			oldObj: invalid,
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Annotations[config.AnnotationPriority] = "higher"
+			},
+			wantMessage: "invalid priority annotation",
+		},
+		{
+			name:   "updating an invalid runner set without touching its annotations",
+			oldObj: invalid,
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Finalizers = []string{"actions.github.com/cleanup-protection"}
+				runnerSet.Annotations[config.AnnotationStatus] = `{"error":"invalid priority annotation"}`
+			},
+			wantAllowed: true,
+		},
+		{
+			name:   "opting out an invalid runner set",
+			oldObj: invalid,
+			modify: func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) {
+				runnerSet.Annotations[config.AnnotationEnabled] = "false"
+			},
+			wantAllowed: true,
+		},
+	}
+
+	handler := newRunnerSetWebhook(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newObj := tt.oldObj.DeepCopy()
+			tt.modify(newObj)
+			response := handler.Handle(context.Background(), admissionRequest(t, admissionv1.Update, newObj, tt.oldObj))
+			assertAdmission(t, response, tt.wantAllowed, tt.wantMessage)
+		})
+	}
+}
+
+// Helper functions
+
+func newRunnerSetWebhook(t *testing.T) *admission.Webhook {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := actionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register AutoscalingRunnerSet scheme: %v", err)
+	}
+	return admission.WithCustomValidator(scheme, &actionsv1alpha1.AutoscalingRunnerSet{}, &RunnerSetValidator{})
+}
+
+// admissionRequest builds the admission request the API server sends for an operation on a runner set
+func admissionRequest(t *testing.T, operation admissionv1.Operation, obj, oldObj *actionsv1alpha1.AutoscalingRunnerSet) admission.Request {
+	t.Helper()
+
+	encode := func(runnerSet *actionsv1alpha1.AutoscalingRunnerSet) runtime.RawExtension {
+		if runnerSet == nil {
+			return runtime.RawExtension{}
+		}
+		runnerSet = runnerSet.DeepCopy()
+		runnerSet.APIVersion = actionsv1alpha1.GroupVersion.String()
+		runnerSet.Kind = "AutoscalingRunnerSet"
+		raw, err := json.Marshal(runnerSet)
+		if err != nil {
+			t.Fatalf("failed to marshal runner set: %v", err)
+		}
+		return runtime.RawExtension{Raw: raw}
+	}
+
+	return admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       "request-uid",
+			Operation: operation,
+			Namespace: obj.Namespace,
+			Name:      obj.Name,
+			Object:    encode(obj),
+			OldObject: encode(oldObj),
+		},
+	}
+}
+
+func assertAdmission(t *testing.T, response admission.Response, wantAllowed bool, wantMessage string) {
+	t.Helper()
+
+	if response.Allowed != wantAllowed {
+		t.Fatalf("Allowed = %v, want %v (result: %+v)", response.Allowed, wantAllowed, response.Result)
+	}
+	if wantMessage != "" && !strings.Contains(response.Result.Message, wantMessage) {
+		t.Errorf("Message = %q, want containing %q", response.Result.Message, wantMessage)
+	}
+}